@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	auth "github.com/abbot/go-http-auth"
+)
+
+const auditRedacted = "<redacted>"
+
+var (
+	auditMutex = &sync.Mutex{}
+	// auditRequestsMutex : one audited request at a time for files changed by this request only
+	auditRequestsMutex = &sync.Mutex{}
+)
+
+// auditResponseWriter : http.ResponseWriter for keep status code and body of response sent to client.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (aw *auditResponseWriter) WriteHeader(statusCode int) {
+	if aw.statusCode == 0 {
+		aw.statusCode = statusCode
+	}
+	aw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (aw *auditResponseWriter) Write(b []byte) (int, error) {
+	if aw.statusCode == 0 {
+		aw.statusCode = http.StatusOK
+	}
+	aw.body.Write(b)
+
+	return aw.ResponseWriter.Write(b)
+}
+
+// redactJSON : replace value of Auth_pass and auth_pass in content of files (snapshot) in json decoded.
+func redactJSON(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, subValue := range typed {
+			content, isString := subValue.(string)
+			switch {
+			case strings.EqualFold(key, "auth_pass"):
+				typed[key] = auditRedacted
+			case strings.EqualFold(key, "content") && isString:
+				typed[key] = redactFile(content)
+			default:
+				typed[key] = redactJSON(subValue)
+			}
+		}
+	case []interface{}:
+		for i, subValue := range typed {
+			typed[i] = redactJSON(subValue)
+		}
+	}
+
+	return value
+}
+
+// redactFile : replace auth_pass in content of keepalived file.
+func redactFile(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "auth_pass ") {
+			lines[i] = strings.Join([]string{
+				strings.SplitN(line, "auth_pass ", 2)[0], "auth_pass ", auditRedacted}, "")
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// readAuditFiles : files in managed directories on each node (read with mutex locked).
+func readAuditFiles() (map[string][]managedFileType, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	files := make(map[string][]managedFileType)
+	for _, node := range nodesOps() {
+		nodeFiles, err := node.readFiles()
+		if err != nil {
+			return files, fmt.Errorf("read files on %v : %v", node.name, err) // nolint: errorlint
+		}
+		files[node.name] = nodeFiles
+	}
+
+	return files, nil
+}
+
+// diffAuditFiles : files created, changed or removed on each node between before and after.
+func diffAuditFiles(before, after map[string][]managedFileType) []auditFileType {
+	auditFiles := make([]auditFileType, 0)
+	for _, node := range nodesOps() {
+		beforeFiles := make(map[string]string)
+		for _, file := range before[node.name] {
+			if file.Exists {
+				beforeFiles[file.Path] = file.Content
+			}
+		}
+		afterFiles := make(map[string]string)
+		for _, file := range after[node.name] {
+			if file.Exists {
+				afterFiles[file.Path] = file.Content
+			}
+		}
+		for _, file := range after[node.name] {
+			contentBefore, ok := beforeFiles[file.Path]
+			switch {
+			case !file.Exists:
+				continue
+			case !ok:
+				auditFiles = append(auditFiles, auditFileType{
+					Created: true,
+					Node:    node.name,
+					Path:    file.Path,
+					After:   redactFile(file.Content),
+				})
+			case contentBefore != file.Content:
+				auditFiles = append(auditFiles, auditFileType{
+					Node:   node.name,
+					Path:   file.Path,
+					Before: redactFile(contentBefore),
+					After:  redactFile(file.Content),
+				})
+			}
+		}
+		for _, file := range before[node.name] {
+			if _, ok := afterFiles[file.Path]; file.Exists && !ok {
+				auditFiles = append(auditFiles, auditFileType{
+					Removed: true,
+					Node:    node.name,
+					Path:    file.Path,
+					Before:  redactFile(file.Content),
+				})
+			}
+		}
+	}
+
+	return auditFiles
+}
+
+// recordAudit : append audit in -audit_log.
+func recordAudit(audit auditType) error {
+	auditMutex.Lock()
+	defer auditMutex.Unlock()
+	auditLog, err := os.OpenFile(configAuditLog(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	defer auditLog.Close()
+
+	return json.NewEncoder(auditLog).Encode(audit)
+}
+
+// readAudits : read -audit_log with filters on user and endpoint (prefix), only the 'limit' last if limit > 0.
+func readAudits(user, endpoint string, limit int) ([]auditType, error) {
+	auditMutex.Lock()
+	defer auditMutex.Unlock()
+	audits := make([]auditType, 0)
+	auditLog, err := os.Open(configAuditLog())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return audits, nil
+		}
+
+		return audits, err
+	}
+	defer auditLog.Close()
+	scanner := bufio.NewScanner(auditLog)
+	// file contents before/after are in one line
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 64*1024*1024)
+	for scanner.Scan() {
+		var audit auditType
+		err := json.Unmarshal(scanner.Bytes(), &audit)
+		if err != nil {
+			log.Printf("audit ignored : %v", err)
+
+			continue
+		}
+		if (user != "" && audit.User != user) || !strings.HasPrefix(audit.Endpoint, endpoint) {
+			continue
+		}
+		audits = append(audits, audit)
+	}
+	err = scanner.Err()
+	if err != nil {
+		return audits, err
+	}
+	if limit > 0 && len(audits) > limit {
+		audits = audits[len(audits)-limit:]
+	}
+
+	return audits, nil
+}
+
+// auditMiddleware : record authenticated requests which change configuration with user,
+// json submitted (Auth_pass redacted), files changed on each node and response in -audit_log.
+func auditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if configAuditLog() == "" || !mutatingRequest(r) {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+		if configHtpasswd() != "" {
+			htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+			authenticator := auth.BasicAuth{
+				Realm:   "Basic Realm",
+				Secrets: htpasswd,
+			}
+			usercheck := authenticator.CheckAuth(r)
+			if usercheck == "" {
+				// refused by handler, nothing to audit
+				next.ServeHTTP(w, r)
+
+				return
+			}
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		user, _, _ := r.BasicAuth()
+		audit := auditType{
+			Date:     time.Now().UTC().Format(time.RFC3339),
+			User:     user,
+			Method:   r.Method,
+			Endpoint: r.URL.Path,
+		}
+		if len(bytes.TrimSpace(body)) != 0 {
+			var request interface{}
+			if err := json.Unmarshal(body, &request); err != nil {
+				audit.Request = "invalid json"
+			} else {
+				audit.Request = redactJSON(request)
+			}
+		}
+		auditRequestsMutex.Lock()
+		defer auditRequestsMutex.Unlock()
+		filesBefore, errBefore := readAuditFiles()
+		aw := &auditResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(aw, r)
+		if aw.statusCode == 0 {
+			aw.statusCode = http.StatusOK
+		}
+		audit.StatusCode = aw.statusCode
+		audit.Result = strings.TrimSpace(aw.body.String())
+		filesAfter, errAfter := readAuditFiles()
+		switch {
+		case errBefore != nil:
+			audit.FilesError = errBefore.Error()
+		case errAfter != nil:
+			audit.FilesError = errAfter.Error()
+		default:
+			audit.Files = diffAuditFiles(filesBefore, filesAfter)
+		}
+		err = recordAudit(audit)
+		if err != nil {
+			log.Printf("audit of %v %v error : %v", r.Method, r.URL.Path, err)
+		}
+	})
+}
+
+// listAudit : on master API for read audit log (?user=, ?endpoint= prefix of url and ?limit= for the last),
+// without body.
+func listAudit(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	limit := 0
+	if r.URL.Query().Get("limit") != "" {
+		var err error
+		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, "bad limit", err)
+
+			return
+		}
+	}
+	audits, err := readAudits(r.URL.Query().Get("user"), r.URL.Query().Get("endpoint"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	js, err := json.Marshal(audits)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
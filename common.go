@@ -1,13 +1,14 @@
 package main
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -19,11 +20,48 @@ const (
 	maxLengthVRRPIDForVmacNoShort        = 4
 	maxVIPinVirtualIPaddress             = 20
 	permissionFileCreated                = 0o755
+	realServerAdd                        = "add"
+	realServerRemove                     = "remove"
+	realServerDrain                      = "drain"
+	snapshotVersion                      = 1
 )
 
+// nodeIP : IP of this node in IP_nodes with -node_name, IP_master or IP_slave otherwise.
+func nodeIP(ifaceVrrp ifaceVrrpType) string {
+	if ip, ok := ifaceVrrp.IPNodes[*nodeName]; ok && *nodeName != "" {
+		return ip
+	}
+	if *isSlave {
+		return ifaceVrrp.IPSlave
+	}
+
+	return ifaceVrrp.IPMaster
+}
+
+// nodePrio : priority of this node in Prio_nodes with -node_name, Prio_master or Prio_slave otherwise.
+func nodePrio(ifaceVrrp ifaceVrrpType) string {
+	if prio, ok := ifaceVrrp.PrioNodes[*nodeName]; ok && *nodeName != "" {
+		return prio
+	}
+	if *isSlave {
+		return ifaceVrrp.PrioSlave
+	}
+
+	return ifaceVrrp.PrioMaster
+}
+
+// peerIP : IP of peer in IP_nodes, IP_slave otherwise.
+func peerIP(ifaceVrrp ifaceVrrpType, peer peerType) string {
+	if ip, ok := ifaceVrrp.IPNodes[peer.Name]; ok {
+		return ip
+	}
+
+	return ifaceVrrp.IPSlave
+}
+
 // function check if iface exist.
 func checkIfaceExists(ifaceVrrp ifaceVrrpType) bool {
-	_, err := os.Stat(strings.Join([]string{"/etc/network/interfaces.d/", ifaceVrrp.Iface}, ""))
+	_, err := os.Stat(strings.Join([]string{ifaceConfDir(), ifaceVrrp.Iface}, ""))
 
 	return !os.IsNotExist(err)
 }
@@ -31,49 +69,26 @@ func checkIfaceExists(ifaceVrrp ifaceVrrpType) bool {
 // generate /etc/network/ file for check/add.
 func generateIfaceFile(ifaceVrrp ifaceVrrpType, postupAdd bool) string {
 	var ifaceIn string
-	if *isSlave {
-		if strings.Contains(ifaceVrrp.IPSlave, ":") {
+	ipNode := nodeIP(ifaceVrrp)
+	if strings.Contains(ipNode, ":") {
+		ifaceIn = strings.Join([]string{
+			"auto ", ifaceVrrp.Iface, "\n",
+			"iface ", ifaceVrrp.Iface, " inet6 static\n",
+			"\taddress ", ipNode, "/", ifaceVrrp.Mask, "\n",
+		}, "")
+	} else {
+		if ipNode == "" {
 			ifaceIn = strings.Join([]string{
 				"auto ", ifaceVrrp.Iface, "\n",
-				"iface ", ifaceVrrp.Iface, " inet6 static\n",
-				"\taddress ", ifaceVrrp.IPSlave, "/", ifaceVrrp.Mask, "\n",
+				"iface ", ifaceVrrp.Iface, " inet manual\n",
+				"\tup ifconfig ", ifaceVrrp.Iface, " up\n",
 			}, "")
 		} else {
-			if ifaceVrrp.IPSlave == "" {
-				ifaceIn = strings.Join([]string{
-					"auto ", ifaceVrrp.Iface, "\n",
-					"iface ", ifaceVrrp.Iface, " inet manual\n",
-					"\tup ifconfig ", ifaceVrrp.Iface, " up\n",
-				}, "")
-			} else {
-				ifaceIn = strings.Join([]string{
-					"auto ", ifaceVrrp.Iface, "\n",
-					"iface ", ifaceVrrp.Iface, " inet static\n",
-					"\taddress ", ifaceVrrp.IPSlave, "/", ifaceVrrp.Mask, "\n",
-				}, "")
-			}
-		}
-	} else {
-		if strings.Contains(ifaceVrrp.IPMaster, ":") {
 			ifaceIn = strings.Join([]string{
 				"auto ", ifaceVrrp.Iface, "\n",
-				"iface ", ifaceVrrp.Iface, " inet6 static\n",
-				"\taddress ", ifaceVrrp.IPMaster, "/", ifaceVrrp.Mask, "\n",
+				"iface ", ifaceVrrp.Iface, " inet static\n",
+				"\taddress ", ipNode, "/", ifaceVrrp.Mask, "\n",
 			}, "")
-		} else {
-			if ifaceVrrp.IPMaster == "" {
-				ifaceIn = strings.Join([]string{
-					"auto ", ifaceVrrp.Iface, "\n",
-					"iface ", ifaceVrrp.Iface, " inet manual\n",
-					"\tup ifconfig ", ifaceVrrp.Iface, " up\n",
-				}, "")
-			} else {
-				ifaceIn = strings.Join([]string{
-					"auto ", ifaceVrrp.Iface, "\n",
-					"iface ", ifaceVrrp.Iface, " inet static\n",
-					"\taddress ", ifaceVrrp.IPMaster, "/", ifaceVrrp.Mask, "\n",
-				}, "")
-			}
 		}
 	}
 	if (ifaceVrrp.VlanDevice != "") && (strings.Contains(ifaceVrrp.Iface, "vlan")) {
@@ -128,15 +143,15 @@ func generateIfaceFile(ifaceVrrp ifaceVrrpType, postupAdd bool) string {
 func checkIfaceOk(ifaceVrrp ifaceVrrpType) (bool, error) {
 	ifaceIn := generateIfaceFile(ifaceVrrp, true)
 
-	ifaceReadByte, err := ioutil.ReadFile(strings.Join([]string{"/etc/network/interfaces.d/", ifaceVrrp.Iface}, ""))
+	ifaceReadByte, err := ioutil.ReadFile(strings.Join([]string{ifaceConfDir(), ifaceVrrp.Iface}, ""))
 	ifaceRead := string(ifaceReadByte)
 	if err != nil {
 		return false, err
 	}
 	if ifaceIn == ifaceRead {
-		err := exec.Command("ifquery", ifaceVrrp.Iface, "--state").Run()
+		_, err := executor.run("ifquery", ifaceVrrp.Iface, "--state")
 		if err != nil {
-			if *debug {
+			if configDebug() {
 				log.Printf("ifquery %v --state failed", ifaceVrrp.Iface)
 			}
 
@@ -145,7 +160,7 @@ func checkIfaceOk(ifaceVrrp ifaceVrrpType) (bool, error) {
 
 		return true, nil
 	}
-	if *debug {
+	if configDebug() {
 		log.Printf("File from json : %#v", ifaceIn)
 		log.Printf("File read : %#v", ifaceRead)
 	}
@@ -157,7 +172,7 @@ func checkIfaceOk(ifaceVrrp ifaceVrrpType) (bool, error) {
 func checkIfaceWithoutPostup(ifaceVrrp ifaceVrrpType) (bool, error) {
 	ifaceIn := generateIfaceFile(ifaceVrrp, false)
 
-	ifaceReadByte, err := ioutil.ReadFile(strings.Join([]string{"/etc/network/interfaces.d/", ifaceVrrp.Iface}, ""))
+	ifaceReadByte, err := ioutil.ReadFile(strings.Join([]string{ifaceConfDir(), ifaceVrrp.Iface}, ""))
 	ifaceRead := string(ifaceReadByte)
 	if err != nil {
 		return false, err
@@ -167,7 +182,7 @@ func checkIfaceWithoutPostup(ifaceVrrp ifaceVrrpType) (bool, error) {
 	if ifaceIn == ifaceRead {
 		return true, nil
 	}
-	if *debug {
+	if configDebug() {
 		log.Printf("File from json : %#v", ifaceIn)
 		log.Printf("File read : %#v", ifaceRead)
 	}
@@ -184,15 +199,10 @@ func addIfaceFile(ifaceVrrp ifaceVrrpType) error {
 		ipVersCmd = "-6"
 	}
 	if ifaceVrrp.DefaultGW != "" {
-		cmd := exec.Command("ip", ipVersCmd, "route")
-		stdout, err := cmd.StdoutPipe()
+		returnCmd, err := executor.run("ip", ipVersCmd, "route")
 		if err != nil {
 			return err
 		}
-		if err := cmd.Start(); err != nil {
-			return err
-		}
-		returnCmd, _ := ioutil.ReadAll(stdout)
 		if (strings.Contains(string(returnCmd), "default")) &&
 			(!strings.Contains(string(returnCmd), strings.Join([]string{
 				"default", "via", ifaceVrrp.DefaultGW,
@@ -201,7 +211,7 @@ func addIfaceFile(ifaceVrrp ifaceVrrpType) error {
 		}
 	}
 
-	err := ioutil.WriteFile(strings.Join([]string{"/etc/network/interfaces.d/", ifaceVrrp.Iface}, ""),
+	err := ioutil.WriteFile(strings.Join([]string{ifaceConfDir(), ifaceVrrp.Iface}, ""),
 		[]byte(ifaceIn), 0o644)
 	if err != nil {
 		return err
@@ -216,21 +226,27 @@ func addIface(ifaceVrrp ifaceVrrpType) error {
 	if err != nil {
 		return err
 	}
-	cmdOut, err := exec.Command("ifup", ifaceVrrp.Iface).CombinedOutput()
+
+	return ifupIface(ifaceVrrp.Iface)
+}
+
+// ifupIface : ifup interface and check state.
+func ifupIface(iface string) error {
+	cmdOut, err := executor.run("ifup", iface)
 	if err != nil {
 		return fmt.Errorf(string(cmdOut), err.Error())
 	}
-	err = exec.Command("ifquery", ifaceVrrp.Iface, "--state").Run()
+	_, err = executor.run("ifquery", iface, "--state")
 	if err != nil {
-		return fmt.Errorf("error on ifup %v", ifaceVrrp.Iface)
+		return fmt.Errorf("error on ifup %v", iface)
 	}
 
-	return nil
+	return waitIfaceUp(iface)
 }
 
 // removeIfaceFile : remove network config file.
 func removeIfaceFile(ifaceVrrp ifaceVrrpType) error {
-	err := os.Remove(strings.Join([]string{"/etc/network/interfaces.d/", ifaceVrrp.Iface}, ""))
+	err := os.Remove(strings.Join([]string{ifaceConfDir(), ifaceVrrp.Iface}, ""))
 	if err != nil {
 		return err
 	}
@@ -240,15 +256,15 @@ func removeIfaceFile(ifaceVrrp ifaceVrrpType) error {
 
 // removeIface : ifdown iface and network config file.
 func removeIface(ifaceVrrp ifaceVrrpType) error {
-	VGs, err := ioutil.ReadDir("/etc/keepalived/keepalived-vrrp.d/")
+	VGs, err := ioutil.ReadDir(keepalivedConfDir())
 	if err != nil {
-		return fmt.Errorf("error for readdir /etc/keepalived/keepalived-vrrp.d/")
+		return fmt.Errorf("error for readdir %v", keepalivedConfDir())
 	}
 	for _, VG := range VGs {
 		if strings.HasSuffix(VG.Name(), ".conf") {
 			continue
 		}
-		files, err := filepath.Glob(strings.Join([]string{"/etc/keepalived/keepalived-vrrp.d/", VG.Name(), "/*.conf"}, ""))
+		files, err := filepath.Glob(strings.Join([]string{keepalivedConfDir(), VG.Name(), "/*.conf"}, ""))
 		if err != nil {
 			return err
 		}
@@ -269,15 +285,7 @@ func removeIface(ifaceVrrp ifaceVrrpType) error {
 			}
 		}
 	}
-	if len(ifaceVrrp.PostUp) != 0 {
-		for _, post := range ifaceVrrp.PostUp {
-			err := reversePostUp(post)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	err = exec.Command("ifdown", ifaceVrrp.Iface, "--force").Run()
+	err = ifdownIface(ifaceVrrp)
 	if err != nil {
 		return err
 	}
@@ -289,10 +297,26 @@ func removeIface(ifaceVrrp ifaceVrrpType) error {
 	return nil
 }
 
+// ifdownIface : reverse post-up commands and ifdown iface (network config file kept).
+func ifdownIface(ifaceVrrp ifaceVrrpType) error {
+	for _, post := range ifaceVrrp.PostUp {
+		err := reversePostUp(post)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := executor.run("ifdown", ifaceVrrp.Iface, "--force")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // checkVrrpExists: check if vrrp config file exist.
 func checkVrrpExists(ifaceVrrp ifaceVrrpType) bool {
 	_, err := os.Stat(strings.Join([]string{
-		"/etc/keepalived/keepalived-vrrp.d/",
+		keepalivedConfDir(),
 		ifaceVrrp.VrrpGroup, "/", ifaceVrrp.Iface, "_", ifaceVrrp.IDVrrp, ".conf",
 	}, ""))
 
@@ -302,16 +326,16 @@ func checkVrrpExists(ifaceVrrp ifaceVrrpType) bool {
 // checkVrrpExistsOtherVG : check if vrrp config file exist in other vrrp group directory.
 func checkVrrpExistsOtherVG(ifaceVrrp ifaceVrrpType) (string, error) {
 	VGReturn := ""
-	VGs, err := ioutil.ReadDir("/etc/keepalived/keepalived-vrrp.d/")
+	VGs, err := ioutil.ReadDir(keepalivedConfDir())
 	if err != nil {
-		return VGReturn, fmt.Errorf("readdir /etc/keepalived/keepalived-vrrp.d/ error")
+		return VGReturn, fmt.Errorf("readdir %v error", keepalivedConfDir())
 	}
 	for _, VG := range VGs {
 		if strings.HasSuffix(VG.Name(), ".conf") {
 			continue
 		}
 		_, err := os.Stat(strings.Join([]string{
-			"/etc/keepalived/keepalived-vrrp.d/",
+			keepalivedConfDir(),
 			VG.Name(), "/", ifaceVrrp.Iface, "_", ifaceVrrp.IDVrrp, ".conf",
 		}, ""))
 
@@ -323,6 +347,39 @@ func checkVrrpExistsOtherVG(ifaceVrrp ifaceVrrpType) (string, error) {
 	return VGReturn, nil
 }
 
+// vrrpInstanceName : name of vrrp_instance in vrrp config file.
+func vrrpInstanceName(ifaceVrrp ifaceVrrpType) string {
+	if ifaceVrrp.SyncIface != "" {
+		// shortname for bug check arguments on lvs_sync_daemon keepalived v2.x
+		// -> 'lvs_sync_daemon vrrp interface name 'network_XXXX_id_YY' too long - ignoring'
+		return strings.Join([]string{ifaceVrrp.Iface, "_id_", ifaceVrrp.IDVrrp}, "")
+	}
+
+	return strings.Join([]string{"network_", ifaceVrrp.Iface, "_id_", ifaceVrrp.IDVrrp}, "")
+}
+
+// vmacIfaceName : name of vmac iface created by keepalived with use_vmac, empty without vmac (or with IPv6).
+func vmacIfaceName(ifaceVrrp ifaceVrrpType) string {
+	if !ifaceVrrp.UseVmac {
+		return ""
+	}
+	for _, vip := range ifaceVrrp.IPVip {
+		if strings.Contains(vip, ":") {
+			return ""
+		}
+	}
+	ifaceCut := strings.Split(ifaceVrrp.Iface, ":")[0]
+	switch {
+	case (strings.Count(ifaceCut, "") < maxLengthInterfaceNameForVmacNoShort-1) &&
+		(strings.Count(ifaceVrrp.IDVrrp, "") < maxLengthVRRPIDForVmacNoShort):
+		return strings.Join([]string{"vmac_", ifaceCut, "_", ifaceVrrp.IDVrrp}, "")
+	case strings.Count(ifaceCut, "") < maxLengthInterfaceNameForVmacNoShort:
+		return strings.Join([]string{"vc_", ifaceCut, "_", ifaceVrrp.IDVrrp}, "")
+	}
+
+	return ""
+}
+
 // function generate vrrp file string.
 func generateVrrpFile(ifaceVrrp ifaceVrrpType, syncAdd bool) (string, error) {
 	version := ipv4str
@@ -332,15 +389,7 @@ func generateVrrpFile(ifaceVrrp ifaceVrrpType, syncAdd bool) (string, error) {
 		}
 	}
 	ifaceCut := strings.Split(ifaceVrrp.Iface, ":")[0]
-	vrrpIn := "vrrp_instance "
-	if ifaceVrrp.SyncIface != "" {
-		// shortname for bug check arguments on lvs_sync_daemon keepalived v2.x
-		// -> 'lvs_sync_daemon vrrp interface name 'network_XXXX_id_YY' too long - ignoring'
-		vrrpIn = strings.Join([]string{vrrpIn, ifaceVrrp.Iface, "_id_", ifaceVrrp.IDVrrp}, "")
-	} else {
-		vrrpIn = strings.Join([]string{vrrpIn, "network_", ifaceVrrp.Iface, "_id_", ifaceVrrp.IDVrrp}, "")
-	}
-	vrrpIn = strings.Join([]string{vrrpIn, " {\n", "\tstate BACKUP\n"}, "")
+	vrrpIn := strings.Join([]string{"vrrp_instance ", vrrpInstanceName(ifaceVrrp), " {\n", "\tstate BACKUP\n"}, "")
 	if syncAdd {
 		if ifaceVrrp.IfaceForVrrp != "" {
 			vrrpIn = strings.Join([]string{vrrpIn, "\tinterface ", ifaceVrrp.IfaceForVrrp, "\n"}, "")
@@ -385,16 +434,15 @@ func generateVrrpFile(ifaceVrrp ifaceVrrpType, syncAdd bool) (string, error) {
 		vrrpIn = strings.Join([]string{vrrpIn, "\tgarp_master_refresh ", ifaceVrrp.GarpMasterRefresh, "\n"}, "")
 	}
 	vrrpIn = strings.Join([]string{vrrpIn, "\tvirtual_router_id ", ifaceVrrp.IDVrrp, "\n"}, "")
-	if *isSlave {
-		vrrpIn = strings.Join([]string{vrrpIn, "\tpriority ", ifaceVrrp.PrioSlave, "\n"}, "")
-	} else {
-		vrrpIn = strings.Join([]string{vrrpIn, "\tpriority ", ifaceVrrp.PrioMaster, "\n"}, "")
-	}
+	vrrpIn = strings.Join([]string{vrrpIn, "\tpriority ", nodePrio(ifaceVrrp), "\n"}, "")
 	if ifaceVrrp.AdvertInt != "" {
 		vrrpIn = strings.Join([]string{vrrpIn, "\tadvert_int ", ifaceVrrp.AdvertInt, "\n"}, "")
 	} else {
 		vrrpIn = strings.Join([]string{vrrpIn, "\tadvert_int 1\n"}, "")
 	}
+	if *notifyCommand != "" {
+		vrrpIn = strings.Join([]string{vrrpIn, "\tnotify \"", *notifyCommand, "\"\n"}, "")
+	}
 	if (ifaceVrrp.AuthType != "") && (version != ipv6str) {
 		vrrpIn = strings.Join([]string{
 			vrrpIn, "\tauthentication {\n",
@@ -469,7 +517,7 @@ func checkVrrpOk(ifaceVrrp ifaceVrrpType) (bool, error) {
 		return false, err
 	}
 	vrrpReadByte, err := ioutil.ReadFile(strings.Join([]string{
-		"/etc/keepalived/keepalived-vrrp.d/",
+		keepalivedConfDir(),
 		ifaceVrrp.VrrpGroup, "/", ifaceVrrp.Iface, "_", ifaceVrrp.IDVrrp, ".conf",
 	}, ""))
 
@@ -477,10 +525,10 @@ func checkVrrpOk(ifaceVrrp ifaceVrrpType) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	if vrrpIn == vrrpRead {
+	if withoutNotifyLine(vrrpIn) == withoutNotifyLine(vrrpRead) {
 		return true, nil
 	}
-	if *debug {
+	if configDebug() {
 		log.Printf("File from json : %#v", vrrpIn)
 		log.Printf("File read : %#v", vrrpRead)
 	}
@@ -488,6 +536,14 @@ func checkVrrpOk(ifaceVrrp ifaceVrrpType) (bool, error) {
 	return false, nil
 }
 
+// withoutNotifyLine : vrrp config file without notify line, files written before -notify_cmd
+// (or with another command) are still ok and the line is added on next change of vrrp instance.
+func withoutNotifyLine(vrrpFile string) string {
+	re := regexp.MustCompile("\tnotify .*\n")
+
+	return re.ReplaceAllString(vrrpFile, "")
+}
+
 // checkVrrpWithoutSync : check vrrp config file without interface line (move interface vrrp packet).
 func checkVrrpWithoutSync(ifaceVrrp ifaceVrrpType) (bool, error) {
 	vrrpIn, err := generateVrrpFile(ifaceVrrp, false)
@@ -495,7 +551,7 @@ func checkVrrpWithoutSync(ifaceVrrp ifaceVrrpType) (bool, error) {
 		return false, err
 	}
 	vrrpReadByte, err := ioutil.ReadFile(strings.Join([]string{
-		"/etc/keepalived/keepalived-vrrp.d/",
+		keepalivedConfDir(),
 		ifaceVrrp.VrrpGroup, "/", ifaceVrrp.Iface, "_", ifaceVrrp.IDVrrp, ".conf",
 	}, ""))
 
@@ -505,10 +561,10 @@ func checkVrrpWithoutSync(ifaceVrrp ifaceVrrpType) (bool, error) {
 	}
 	re := regexp.MustCompile("\tinterface.*\n")
 	vrrpRead = re.ReplaceAllString(vrrpRead, "")
-	if vrrpIn == vrrpRead {
+	if withoutNotifyLine(vrrpIn) == withoutNotifyLine(vrrpRead) {
 		return true, nil
 	}
-	if *debug {
+	if configDebug() {
 		log.Printf("File from json : %#v", vrrpIn)
 		log.Printf("File read : %#v", vrrpRead)
 	}
@@ -523,10 +579,10 @@ func addVrrp(ifaceVrrp ifaceVrrpType) error {
 		return err
 	}
 
-	_, err = os.Stat(strings.Join([]string{"/etc/keepalived/keepalived-vrrp.d/", ifaceVrrp.VrrpGroup}, ""))
+	_, err = os.Stat(strings.Join([]string{keepalivedConfDir(), ifaceVrrp.VrrpGroup}, ""))
 	if os.IsNotExist(err) {
 		err := os.Mkdir(strings.Join([]string{
-			"/etc/keepalived/keepalived-vrrp.d/",
+			keepalivedConfDir(),
 			ifaceVrrp.VrrpGroup,
 		}, ""), os.FileMode(permissionFileCreated))
 		if err != nil {
@@ -534,7 +590,7 @@ func addVrrp(ifaceVrrp ifaceVrrpType) error {
 		}
 	}
 	err = ioutil.WriteFile(strings.Join([]string{
-		"/etc/keepalived/keepalived-vrrp.d/",
+		keepalivedConfDir(),
 		ifaceVrrp.VrrpGroup, "/", ifaceVrrp.Iface, "_", ifaceVrrp.IDVrrp, ".conf",
 	}, ""), []byte(vrrpIn), 0o644)
 	if err != nil {
@@ -547,7 +603,7 @@ func addVrrp(ifaceVrrp ifaceVrrpType) error {
 // remove vrrp configuration file.
 func removeVrrp(ifaceVrrp ifaceVrrpType) error {
 	err := os.Remove(strings.Join([]string{
-		"/etc/keepalived/keepalived-vrrp.d/",
+		keepalivedConfDir(),
 		ifaceVrrp.VrrpGroup, "/", ifaceVrrp.Iface, "_", ifaceVrrp.IDVrrp, ".conf",
 	}, ""))
 	if err != nil {
@@ -559,16 +615,16 @@ func removeVrrp(ifaceVrrp ifaceVrrpType) error {
 
 // create vrrp_sync_group configuration and reload keepalived daemon.
 func syncGroupAndReload() error {
-	VGs, err := ioutil.ReadDir("/etc/keepalived/keepalived-vrrp.d/")
+	VGs, err := ioutil.ReadDir(keepalivedConfDir())
 	if err != nil {
-		return fmt.Errorf("readdir /etc/keepalived/keepalived-vrrp.d/ error")
+		return fmt.Errorf("readdir %v error", keepalivedConfDir())
 	}
 	for _, VG := range VGs {
 		if strings.HasSuffix(VG.Name(), ".conf") {
 			continue
 		}
 		var instances []string
-		files, err := filepath.Glob(strings.Join([]string{"/etc/keepalived/keepalived-vrrp.d/", VG.Name(), "/*.conf"}, ""))
+		files, err := filepath.Glob(strings.Join([]string{keepalivedConfDir(), VG.Name(), "/*.conf"}, ""))
 		if err != nil {
 			return err
 		}
@@ -587,7 +643,7 @@ func syncGroupAndReload() error {
 				}
 			}
 			if len(instances) == 0 {
-				err := os.RemoveAll(strings.Join([]string{"/etc/keepalived/keepalived-vrrp.d/", VG.Name()}, ""))
+				err := os.RemoveAll(strings.Join([]string{keepalivedConfDir(), VG.Name()}, ""))
 				if err != nil {
 					return fmt.Errorf("error when remove VG empty")
 				}
@@ -596,9 +652,13 @@ func syncGroupAndReload() error {
 				for _, instance := range instances {
 					vrrpSyncGroupIn = strings.Join([]string{vrrpSyncGroupIn, "\t\t", instance, "\n"}, "")
 				}
-				vrrpSyncGroupIn = strings.Join([]string{vrrpSyncGroupIn, "\t}\n}\n"}, "")
+				vrrpSyncGroupIn = strings.Join([]string{vrrpSyncGroupIn, "\t}\n"}, "")
+				if *notifyCommand != "" {
+					vrrpSyncGroupIn = strings.Join([]string{vrrpSyncGroupIn, "\tnotify \"", *notifyCommand, "\"\n"}, "")
+				}
+				vrrpSyncGroupIn = strings.Join([]string{vrrpSyncGroupIn, "}\n"}, "")
 				err := ioutil.WriteFile(strings.Join([]string{
-					"/etc/keepalived/keepalived-vrrp.d/",
+					keepalivedConfDir(),
 					VG.Name(), "/vrrp_sync_group",
 				}, ""), []byte(vrrpSyncGroupIn), 0o644)
 				if err != nil {
@@ -606,7 +666,7 @@ func syncGroupAndReload() error {
 				}
 			}
 		} else {
-			err := os.RemoveAll(strings.Join([]string{"/etc/keepalived/keepalived-vrrp.d/", VG.Name()}, ""))
+			err := os.RemoveAll(strings.Join([]string{keepalivedConfDir(), VG.Name()}, ""))
 			if err != nil {
 				return fmt.Errorf("error when remove VG empty")
 			}
@@ -622,10 +682,11 @@ func syncGroupAndReload() error {
 
 // func reloadVrrp.
 func reloadVrrp() error {
-	reloadKeepalivedCommandParts := strings.Fields(*reloadKeepalivedCommand)
+	reloadKeepalivedCommandParts := strings.Fields(configReloadCommand())
 	reloadKeepalivedCommandBin := reloadKeepalivedCommandParts[0]
 	reloadKeepalivedCommandArgs := reloadKeepalivedCommandParts[1:]
-	cmdOut, err := exec.Command(reloadKeepalivedCommandBin, reloadKeepalivedCommandArgs...).CombinedOutput()
+	cmdOut, err := executor.run(reloadKeepalivedCommandBin, reloadKeepalivedCommandArgs...)
+	observeKeepalivedReload(err)
 	if err != nil {
 		return fmt.Errorf(string(cmdOut), err.Error())
 	}
@@ -633,17 +694,27 @@ func reloadVrrp() error {
 	return nil
 }
 
-// reversePostUp : del route/rule if remove post-up route/rule add.
-func reversePostUp(post string) error {
+// postDown : command for del route/rule added by post-up, empty if post-up isn't route/rule add.
+func postDown(post string) string {
 	if (strings.Contains(post, "route add")) || (strings.Contains(post, "ip rule add")) {
 		postdown := strings.Replace(post, "post-up", "", 1)
 		postdown = strings.Replace(postdown, "route add", "route del", 1)
 		postdown = strings.Replace(postdown, "ip rule add", "ip rule del", 1)
+
+		return strings.TrimSpace(postdown)
+	}
+
+	return ""
+}
+
+// reversePostUp : del route/rule if remove post-up route/rule add.
+func reversePostUp(post string) error {
+	if postdown := postDown(post); postdown != "" {
 		postdownParts := strings.Fields(postdown)
 		postdownCommand := postdownParts[0]
 		postdownArgs := postdownParts[1:]
 
-		cmdOut, err := exec.Command(postdownCommand, postdownArgs...).CombinedOutput()
+		cmdOut, err := executor.run(postdownCommand, postdownArgs...)
 		if err != nil {
 			return fmt.Errorf(postdown, string(cmdOut), err.Error())
 		}
@@ -657,7 +728,7 @@ func addPostUp(postup string) error {
 	postupParts := strings.Fields(postup)
 	postupCommand := postupParts[0]
 	postupArgs := postupParts[1:]
-	cmdOut, err := exec.Command(postupCommand, postupArgs...).CombinedOutput()
+	cmdOut, err := executor.run(postupCommand, postupArgs...)
 	if err != nil {
 		return fmt.Errorf(postup, string(cmdOut), err.Error())
 	}
@@ -667,7 +738,7 @@ func addPostUp(postup string) error {
 
 // changeIfacePostup : change different post-up line with respect to the configuration.
 func changeIfacePostup(ifaceVrrp ifaceVrrpType) error {
-	ifaceReadByte, err := ioutil.ReadFile(strings.Join([]string{"/etc/network/interfaces.d/", ifaceVrrp.Iface}, ""))
+	ifaceReadByte, err := ioutil.ReadFile(strings.Join([]string{ifaceConfDir(), ifaceVrrp.Iface}, ""))
 	ifaceRead := string(ifaceReadByte)
 	if err != nil {
 		return err
@@ -750,7 +821,7 @@ func changeIfacePostup(ifaceVrrp ifaceVrrpType) error {
 // check if vrrp script file exists.
 func checkVrrpScriptExists(vrrpScriptName string) bool {
 	_, err := os.Stat(strings.Join([]string{
-		"/etc/keepalived/keepalived-vrrp.d/",
+		keepalivedConfDir(),
 		"script_", vrrpScriptName, ".conf",
 	}, ""))
 
@@ -761,7 +832,7 @@ func checkVrrpScriptExists(vrrpScriptName string) bool {
 func checkVrrpScriptOk(vrrpScript vrrpScriptType) (bool, error) {
 	scriptIn := generateScriptFile(vrrpScript)
 	scriptReadByte, err := ioutil.ReadFile(strings.Join([]string{
-		"/etc/keepalived/keepalived-vrrp.d/",
+		keepalivedConfDir(),
 		"script_", vrrpScript.Name, ".conf",
 	}, ""))
 
@@ -772,7 +843,7 @@ func checkVrrpScriptOk(vrrpScript vrrpScriptType) (bool, error) {
 	if scriptIn == scriptRead {
 		return true, nil
 	}
-	if *debug {
+	if configDebug() {
 		log.Printf("File from json : %#v", scriptIn)
 		log.Printf("File read : %#v", scriptRead)
 	}
@@ -784,7 +855,7 @@ func checkVrrpScriptOk(vrrpScript vrrpScriptType) (bool, error) {
 func addVrrpScriptFile(vrrpScript vrrpScriptType) error {
 	scriptIn := generateScriptFile(vrrpScript)
 	err := ioutil.WriteFile(strings.Join([]string{
-		"/etc/keepalived/keepalived-vrrp.d/",
+		keepalivedConfDir(),
 		"script_", vrrpScript.Name, ".conf",
 	}, ""), []byte(scriptIn), 0o644)
 	if err != nil {
@@ -797,7 +868,7 @@ func addVrrpScriptFile(vrrpScript vrrpScriptType) error {
 // remove vrrp script file on system.
 func removeVrrpScriptFile(vrrpScript vrrpScriptType) error {
 	err := os.Remove(strings.Join([]string{
-		"/etc/keepalived/keepalived-vrrp.d/",
+		keepalivedConfDir(),
 		"script_", vrrpScript.Name, ".conf",
 	}, ""))
 	if err != nil {
@@ -852,7 +923,7 @@ func readVrrpScriptFile(scriptName string) (vrrpScriptType, error) {
 	var scriptRead vrrpScriptType
 	var err error
 	scriptReadByte, err := ioutil.ReadFile(strings.Join([]string{
-		"/etc/keepalived/keepalived-vrrp.d/",
+		keepalivedConfDir(),
 		"script_", scriptName, ".conf",
 	}, ""))
 	if err != nil {
@@ -913,3 +984,866 @@ func readVrrpScriptFile(scriptName string) (vrrpScriptType, error) {
 
 	return scriptRead, nil
 }
+
+// check if virtual server file exists.
+func checkVirtualServerExists(virtualServerName string) bool {
+	_, err := os.Stat(strings.Join([]string{
+		keepalivedConfDir(),
+		"virtual_server_", virtualServerName, ".conf",
+	}, ""))
+
+	return !os.IsNotExist(err)
+}
+
+// compare virtual server file with a virtualServerType.
+func checkVirtualServerOk(virtualServer virtualServerType) (bool, error) {
+	virtualServerIn := generateVirtualServerFile(virtualServer)
+	virtualServerReadByte, err := ioutil.ReadFile(strings.Join([]string{
+		keepalivedConfDir(),
+		"virtual_server_", virtualServer.Name, ".conf",
+	}, ""))
+
+	virtualServerRead := string(virtualServerReadByte)
+	if err != nil {
+		return false, err
+	}
+	if virtualServerIn == virtualServerRead {
+		return true, nil
+	}
+	if configDebug() {
+		log.Printf("File from json : %#v", virtualServerIn)
+		log.Printf("File read : %#v", virtualServerRead)
+	}
+
+	return false, nil
+}
+
+// add virtual server file on system.
+func addVirtualServerFile(virtualServer virtualServerType) error {
+	virtualServerIn := generateVirtualServerFile(virtualServer)
+	err := ioutil.WriteFile(strings.Join([]string{
+		keepalivedConfDir(),
+		"virtual_server_", virtualServer.Name, ".conf",
+	}, ""), []byte(virtualServerIn), 0o644)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// remove virtual server file on system.
+func removeVirtualServerFile(virtualServer virtualServerType) error {
+	err := os.Remove(strings.Join([]string{
+		keepalivedConfDir(),
+		"virtual_server_", virtualServer.Name, ".conf",
+	}, ""))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// generate virtual server file string.
+func generateVirtualServerFile(virtualServer virtualServerType) string {
+	virtualServerIn := strings.Join([]string{
+		"virtual_server ", virtualServer.VIP, " ", strconv.Itoa(virtualServer.Port), " {\n",
+	}, "")
+	if virtualServer.LbAlgo != "" {
+		virtualServerIn = strings.Join([]string{virtualServerIn, "\tlb_algo ", virtualServer.LbAlgo, "\n"}, "")
+	}
+	if virtualServer.LbKind != "" {
+		virtualServerIn = strings.Join([]string{virtualServerIn, "\tlb_kind ", virtualServer.LbKind, "\n"}, "")
+	}
+	if virtualServer.PersistenceTimeout != 0 {
+		virtualServerIn = strings.Join([]string{
+			virtualServerIn, "\tpersistence_timeout ", strconv.Itoa(virtualServer.PersistenceTimeout), "\n",
+		}, "")
+	}
+	if virtualServer.Protocol != "" {
+		virtualServerIn = strings.Join([]string{virtualServerIn, "\tprotocol ", virtualServer.Protocol, "\n"}, "")
+	}
+	for _, realServer := range virtualServer.RealServers {
+		virtualServerIn = strings.Join([]string{
+			virtualServerIn, "\treal_server ", realServer.IP, " ", strconv.Itoa(realServer.Port), " {\n",
+			"\t\tweight ", strconv.Itoa(realServer.Weight), "\n",
+			generateCheckerString(realServer.Checker),
+			"\t}\n",
+		}, "")
+	}
+	virtualServerIn = strings.Join([]string{virtualServerIn, "}\n"}, "")
+
+	return virtualServerIn
+}
+
+// generate health checker string of a real server.
+func generateCheckerString(checker checkerType) string {
+	if checker.Type == "" {
+		return ""
+	}
+	checkerIn := strings.Join([]string{"\t\t", checker.Type, " {\n"}, "")
+	switch checker.Type {
+	case "HTTP_GET", "SSL_GET":
+		checkerIn = strings.Join([]string{checkerIn, "\t\t\turl {\n", "\t\t\t\tpath ", checker.Path, "\n"}, "")
+		if checker.StatusCode != 0 {
+			checkerIn = strings.Join([]string{checkerIn, "\t\t\t\tstatus_code ", strconv.Itoa(checker.StatusCode), "\n"}, "")
+		}
+		if checker.Digest != "" {
+			checkerIn = strings.Join([]string{checkerIn, "\t\t\t\tdigest ", checker.Digest, "\n"}, "")
+		}
+		checkerIn = strings.Join([]string{checkerIn, "\t\t\t}\n"}, "")
+	case "MISC_CHECK":
+		checkerIn = strings.Join([]string{checkerIn, "\t\t\tmisc_path \"", checker.MiscPath, "\"\n"}, "")
+		if checker.MiscTimeout != 0 {
+			checkerIn = strings.Join([]string{checkerIn, "\t\t\tmisc_timeout ", strconv.Itoa(checker.MiscTimeout), "\n"}, "")
+		}
+	}
+	// MISC_CHECK runs a script and doesn't connect
+	if checker.ConnectPort != 0 && checker.Type != "MISC_CHECK" {
+		checkerIn = strings.Join([]string{checkerIn, "\t\t\tconnect_port ", strconv.Itoa(checker.ConnectPort), "\n"}, "")
+	}
+	if checker.ConnectTimeout != 0 && checker.Type != "MISC_CHECK" {
+		checkerIn = strings.Join([]string{checkerIn, "\t\t\tconnect_timeout ", strconv.Itoa(checker.ConnectTimeout), "\n"}, "")
+	}
+	if checker.Retry != 0 {
+		if semver.Compare(keepalivedVersion, "v2.0.0") == -1 {
+			checkerIn = strings.Join([]string{checkerIn, "\t\t\tnb_get_retry ", strconv.Itoa(checker.Retry), "\n"}, "")
+		} else {
+			checkerIn = strings.Join([]string{checkerIn, "\t\t\tretry ", strconv.Itoa(checker.Retry), "\n"}, "")
+		}
+	}
+	if checker.DelayBeforeRetry != 0 {
+		checkerIn = strings.Join([]string{
+			checkerIn, "\t\t\tdelay_before_retry ", strconv.Itoa(checker.DelayBeforeRetry), "\n",
+		}, "")
+	}
+	checkerIn = strings.Join([]string{checkerIn, "\t\t}\n"}, "")
+
+	return checkerIn
+}
+
+// read virtual server file on system and fill a virtualServerType.
+func readVirtualServerFile(virtualServerName string) (virtualServerType, error) {
+	virtualServerReadByte, err := ioutil.ReadFile(strings.Join([]string{
+		keepalivedConfDir(),
+		"virtual_server_", virtualServerName, ".conf",
+	}, ""))
+	if err != nil {
+		return virtualServerType{}, err
+	}
+
+	return parseVirtualServerFile(virtualServerName, string(virtualServerReadByte))
+}
+
+// parseVirtualServerFile : fill a virtualServerType with content of virtual server file.
+func parseVirtualServerFile(virtualServerName, virtualServerFile string) (virtualServerType, error) {
+	var virtualServerRead virtualServerType
+	var err error
+	if !strings.HasPrefix(virtualServerFile, "virtual_server ") ||
+		!strings.HasSuffix(virtualServerFile, "\n}\n") {
+		return virtualServerRead, fmt.Errorf("the file is bad (not start or end with good character) ")
+	}
+	virtualServerRead.Name = virtualServerName
+	inRealServer := false
+	for _, line := range strings.Split(virtualServerFile, "\n") {
+		switch {
+		case strings.HasPrefix(line, "virtual_server "):
+			lineSplit := strings.Fields(strings.TrimPrefix(line, "virtual_server "))
+			if len(lineSplit) != 3 { // nolint: gomnd
+				return virtualServerRead, fmt.Errorf("virtual server file has bad line %q", line)
+			}
+			virtualServerRead.VIP = lineSplit[0]
+			virtualServerRead.Port, err = strconv.Atoi(lineSplit[1])
+			if err != nil {
+				return virtualServerRead, err
+			}
+		case strings.HasPrefix(line, "\tlb_algo "):
+			virtualServerRead.LbAlgo = strings.TrimPrefix(line, "\tlb_algo ")
+		case strings.HasPrefix(line, "\tlb_kind "):
+			virtualServerRead.LbKind = strings.TrimPrefix(line, "\tlb_kind ")
+		case strings.HasPrefix(line, "\tpersistence_timeout "):
+			virtualServerRead.PersistenceTimeout, err = strconv.Atoi(strings.TrimPrefix(line, "\tpersistence_timeout "))
+			if err != nil {
+				return virtualServerRead, err
+			}
+		case strings.HasPrefix(line, "\tprotocol "):
+			virtualServerRead.Protocol = strings.TrimPrefix(line, "\tprotocol ")
+		case strings.HasPrefix(line, "\treal_server "):
+			lineSplit := strings.Fields(strings.TrimPrefix(line, "\treal_server "))
+			if len(lineSplit) != 3 { // nolint: gomnd
+				return virtualServerRead, fmt.Errorf("virtual server file has bad line %q", line)
+			}
+			var realServer realServerType
+			realServer.IP = lineSplit[0]
+			realServer.Port, err = strconv.Atoi(lineSplit[1])
+			if err != nil {
+				return virtualServerRead, err
+			}
+			virtualServerRead.RealServers = append(virtualServerRead.RealServers, realServer)
+			inRealServer = true
+		case inRealServer && strings.HasPrefix(line, "\t\tweight "):
+			virtualServerRead.RealServers[len(virtualServerRead.RealServers)-1].Weight, err = strconv.Atoi(
+				strings.TrimPrefix(line, "\t\tweight "))
+			if err != nil {
+				return virtualServerRead, err
+			}
+		case inRealServer && line == "\t}":
+			inRealServer = false
+		case inRealServer && strings.HasPrefix(line, "\t\t"):
+			err = readCheckerLine(&virtualServerRead.RealServers[len(virtualServerRead.RealServers)-1].Checker, line)
+			if err != nil {
+				return virtualServerRead, err
+			}
+		case line == "}":
+			continue
+		case line == "":
+			continue
+		default:
+			return virtualServerRead, fmt.Errorf("virtual server file has unknown line %q", line)
+		}
+	}
+
+	return virtualServerRead, nil
+}
+
+// read a health checker line of virtual server file and fill a checkerType.
+func readCheckerLine(checker *checkerType, line string) error {
+	var err error
+	switch {
+	case line == "\t\tTCP_CHECK {" || line == "\t\tHTTP_GET {" || line == "\t\tSSL_GET {" || line == "\t\tMISC_CHECK {":
+		checker.Type = strings.TrimSuffix(strings.TrimPrefix(line, "\t\t"), " {")
+	case strings.HasPrefix(line, "\t\t\t\tpath "):
+		checker.Path = strings.TrimPrefix(line, "\t\t\t\tpath ")
+	case strings.HasPrefix(line, "\t\t\t\tstatus_code "):
+		checker.StatusCode, err = strconv.Atoi(strings.TrimPrefix(line, "\t\t\t\tstatus_code "))
+	case strings.HasPrefix(line, "\t\t\t\tdigest "):
+		checker.Digest = strings.TrimPrefix(line, "\t\t\t\tdigest ")
+	case strings.HasPrefix(line, "\t\t\tmisc_path "):
+		checker.MiscPath = strings.Trim(strings.TrimPrefix(line, "\t\t\tmisc_path "), "\"")
+	case strings.HasPrefix(line, "\t\t\tmisc_timeout "):
+		checker.MiscTimeout, err = strconv.Atoi(strings.TrimPrefix(line, "\t\t\tmisc_timeout "))
+	case strings.HasPrefix(line, "\t\t\tconnect_port "):
+		checker.ConnectPort, err = strconv.Atoi(strings.TrimPrefix(line, "\t\t\tconnect_port "))
+	case strings.HasPrefix(line, "\t\t\tconnect_timeout "):
+		checker.ConnectTimeout, err = strconv.Atoi(strings.TrimPrefix(line, "\t\t\tconnect_timeout "))
+	case strings.HasPrefix(line, "\t\t\tnb_get_retry "):
+		checker.Retry, err = strconv.Atoi(strings.TrimPrefix(line, "\t\t\tnb_get_retry "))
+	case strings.HasPrefix(line, "\t\t\tretry "):
+		checker.Retry, err = strconv.Atoi(strings.TrimPrefix(line, "\t\t\tretry "))
+	case strings.HasPrefix(line, "\t\t\tdelay_before_retry "):
+		checker.DelayBeforeRetry, err = strconv.Atoi(strings.TrimPrefix(line, "\t\t\tdelay_before_retry "))
+	case line == "\t\t\turl {":
+	case line == "\t\t\t}":
+	case line == "\t\t}":
+	default:
+		return fmt.Errorf("virtual server file has unknown line %q", line)
+	}
+
+	return err
+}
+
+// realServerIndex : index of real server (same ip and port) in virtual server, -1 if not found.
+func realServerIndex(virtualServer virtualServerType, realServer realServerType) int {
+	for i, realServerRead := range virtualServer.RealServers {
+		if realServerRead.IP == realServer.IP && realServerRead.Port == realServer.Port {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// changeRealServers : virtual server with real server added (or updated), removed or with weight 0 (drain).
+func changeRealServers(virtualServer virtualServerType, realServer realServerType,
+	action string) (virtualServerType, error) {
+	index := realServerIndex(virtualServer, realServer)
+	realServers := make([]realServerType, 0, len(virtualServer.RealServers)+1)
+	realServers = append(realServers, virtualServer.RealServers...)
+	switch action {
+	case realServerAdd:
+		if index == -1 {
+			realServers = append(realServers, realServer)
+		} else {
+			realServers[index] = realServer
+		}
+	case realServerRemove:
+		if index != -1 {
+			realServers = append(realServers[:index], realServers[index+1:]...)
+		}
+	case realServerDrain:
+		if index == -1 {
+			return virtualServer, fmt.Errorf("real_server %v %v not found in virtual_server %v",
+				realServer.IP, realServer.Port, virtualServer.Name)
+		}
+		realServers[index].Weight = 0
+	default:
+		return virtualServer, fmt.Errorf("unknown action %v for real_server", action)
+	}
+	virtualServer.RealServers = realServers
+
+	return virtualServer, nil
+}
+
+// readInventory : list network config files, vrrp config files, vrrp script files
+// and virtual server files on system with a hash of their content.
+func readInventory() (inventoryType, error) {
+	var inventory inventoryType
+	ifaceFiles, err := ioutil.ReadDir(ifaceConfDir())
+	if err != nil {
+		return inventory, fmt.Errorf("readdir %v error", ifaceConfDir())
+	}
+	for _, ifaceFile := range ifaceFiles {
+		if ifaceFile.IsDir() {
+			continue
+		}
+		hash, err := hashFile(strings.Join([]string{ifaceConfDir(), ifaceFile.Name()}, ""))
+		if err != nil {
+			return inventory, err
+		}
+		inventory.Ifaces = append(inventory.Ifaces, inventoryItemType{
+			Name: ifaceFile.Name(),
+			Hash: hash,
+		})
+	}
+	VGs, err := ioutil.ReadDir(keepalivedConfDir())
+	if err != nil {
+		return inventory, fmt.Errorf("readdir %v error", keepalivedConfDir())
+	}
+	for _, VG := range VGs {
+		if !VG.IsDir() {
+			hash, err := hashFile(strings.Join([]string{keepalivedConfDir(), VG.Name()}, ""))
+			if err != nil {
+				return inventory, err
+			}
+			switch {
+			case strings.HasPrefix(VG.Name(), "script_") && strings.HasSuffix(VG.Name(), ".conf"):
+				inventory.VrrpScripts = append(inventory.VrrpScripts, inventoryItemType{
+					Name: strings.TrimSuffix(strings.TrimPrefix(VG.Name(), "script_"), ".conf"),
+					Hash: hash,
+				})
+			case strings.HasPrefix(VG.Name(), "virtual_server_") && strings.HasSuffix(VG.Name(), ".conf"):
+				inventory.VirtualServers = append(inventory.VirtualServers, inventoryItemType{
+					Name: strings.TrimSuffix(strings.TrimPrefix(VG.Name(), "virtual_server_"), ".conf"),
+					Hash: hash,
+				})
+			}
+
+			continue
+		}
+		files, err := filepath.Glob(strings.Join([]string{keepalivedConfDir(), VG.Name(), "/*.conf"}, ""))
+		if err != nil {
+			return inventory, err
+		}
+		for _, file := range files {
+			fileName := strings.TrimSuffix(filepath.Base(file), ".conf")
+			separator := strings.LastIndex(fileName, "_")
+			if separator == -1 {
+				continue
+			}
+			hash, err := hashFile(file)
+			if err != nil {
+				return inventory, err
+			}
+			inventory.Vrrps = append(inventory.Vrrps, inventoryItemType{
+				Name:      fileName[:separator],
+				VrrpGroup: VG.Name(),
+				IDVrrp:    fileName[separator+1:],
+				Hash:      hash,
+			})
+		}
+	}
+
+	return inventory, nil
+}
+
+// hashFile : sha256 of file content.
+func hashFile(file string) (string, error) {
+	fileByte, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("read file %v error", file)
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(fileByte)), nil
+}
+
+// read network config file on system and fill a ifaceVrrpType.
+func readIfaceFile(iface string) (ifaceVrrpType, error) {
+	ifaceRead := ifaceVrrpType{
+		Iface: iface,
+	}
+	var err error
+	ifaceReadByte, err := ioutil.ReadFile(strings.Join([]string{ifaceConfDir(), iface}, ""))
+	if err != nil {
+		return ifaceRead, err
+	}
+
+	return parseIfaceFile(iface, string(ifaceReadByte))
+}
+
+// parseIfaceFile : fill a ifaceVrrpType with content of network config file.
+func parseIfaceFile(iface, ifaceFile string) (ifaceVrrpType, error) {
+	ifaceRead := ifaceVrrpType{
+		Iface: iface,
+	}
+	if !strings.HasPrefix(ifaceFile, "auto "+iface+"\n") {
+		return ifaceRead, fmt.Errorf("the file is bad (not start with good character) ")
+	}
+	for _, line := range strings.Split(ifaceFile, "\n") {
+		switch {
+		case line == "auto "+iface:
+			continue
+		case strings.HasPrefix(line, "iface "+iface+" "):
+			continue
+		case strings.HasPrefix(line, "\taddress "):
+			addressSplit := strings.Split(strings.TrimPrefix(line, "\taddress "), "/")
+			if len(addressSplit) != 2 { // nolint: gomnd
+				return ifaceRead, fmt.Errorf("network config file has bad line %q", line)
+			}
+			if *isSlave {
+				ifaceRead.IPSlave = addressSplit[0]
+			} else {
+				ifaceRead.IPMaster = addressSplit[0]
+			}
+			ifaceRead.Mask = addressSplit[1]
+		case line == "\tup ifconfig "+iface+" up":
+			continue
+		case strings.HasPrefix(line, "\tvlan-raw-device "):
+			ifaceRead.VlanDevice = strings.TrimPrefix(line, "\tvlan-raw-device ")
+		case strings.HasPrefix(line, "\tgateway "):
+			ifaceRead.DefaultGW = strings.TrimPrefix(line, "\tgateway ")
+		case strings.HasPrefix(line, "\tslaves "):
+			if *isSlave {
+				ifaceRead.LACPSlavesSlave = strings.TrimPrefix(line, "\tslaves ")
+			} else {
+				ifaceRead.LACPSlavesMaster = strings.TrimPrefix(line, "\tslaves ")
+			}
+		case strings.HasPrefix(line, "\tbond_"):
+			continue
+		case line == "\tpost-up echo layer3+4 > /sys/class/net/"+iface+"/bonding/xmit_hash_policy":
+			continue
+		case strings.HasPrefix(line, "\tpost-up "):
+			ifaceRead.PostUp = append(ifaceRead.PostUp, strings.TrimPrefix(line, "\tpost-up "))
+		case line == "":
+			continue
+		default:
+			return ifaceRead, fmt.Errorf("network config file has unknown line %q", line)
+		}
+	}
+
+	return ifaceRead, nil
+}
+
+// read vrrp config file on system and fill a ifaceVrrpType (Vrrp_group, iface and Id_vrrp needed).
+func readVrrpFile(ifaceVrrp ifaceVrrpType) (ifaceVrrpType, error) {
+	vrrpRead := ifaceVrrpType{
+		Iface:     ifaceVrrp.Iface,
+		VrrpGroup: ifaceVrrp.VrrpGroup,
+	}
+	var err error
+	vrrpReadByte, err := ioutil.ReadFile(strings.Join([]string{
+		keepalivedConfDir(),
+		ifaceVrrp.VrrpGroup, "/", ifaceVrrp.Iface, "_", ifaceVrrp.IDVrrp, ".conf",
+	}, ""))
+	if err != nil {
+		return vrrpRead, err
+	}
+
+	return parseVrrpFile(ifaceVrrp, string(vrrpReadByte))
+}
+
+// parseVrrpFile : fill a ifaceVrrpType with content of vrrp config file.
+func parseVrrpFile(ifaceVrrp ifaceVrrpType, vrrpFile string) (ifaceVrrpType, error) {
+	vrrpRead := ifaceVrrpType{
+		Iface:     ifaceVrrp.Iface,
+		VrrpGroup: ifaceVrrp.VrrpGroup,
+	}
+	if !strings.HasPrefix(vrrpFile, "vrrp_instance ") ||
+		!strings.HasSuffix(vrrpFile, "\n}\n") {
+		return vrrpRead, fmt.Errorf("the file is bad (not start or end with good character) ")
+	}
+	ifaceCut := strings.Split(ifaceVrrp.Iface, ":")[0]
+	block := ""
+	for _, line := range strings.Split(vrrpFile, "\n") {
+		switch {
+		case strings.HasPrefix(line, "vrrp_instance "):
+			continue
+		case line == "global_defs {":
+			block = "global_defs"
+		case line == "\ttrack_interface {":
+			block = "track_interface"
+		case line == "\ttrack_script {":
+			block = "track_script"
+		case line == "\tauthentication {":
+			block = "authentication"
+		case line == "\tvirtual_ipaddress {" || line == "\tvirtual_ipaddress_excluded {":
+			block = "virtual_ipaddress"
+		case line == "\t}" || line == "}":
+			block = ""
+		case block == "track_interface":
+			continue
+		case block == "track_script":
+			vrrpRead.TrackScript = append(vrrpRead.TrackScript, strings.TrimPrefix(line, "\t\t"))
+		case block == "authentication" && strings.HasPrefix(line, "\t\tauth_type "):
+			vrrpRead.AuthType = strings.TrimPrefix(line, "\t\tauth_type ")
+		case block == "authentication" && strings.HasPrefix(line, "\t\tauth_pass "):
+			vrrpRead.AuthPass = strings.TrimPrefix(line, "\t\tauth_pass ")
+		case block == "virtual_ipaddress":
+			lineSplit := strings.Fields(line)
+			if len(lineSplit) == 0 {
+				return vrrpRead, fmt.Errorf("vrrp config file has bad line %q", line)
+			}
+			vrrpRead.IPVip = append(vrrpRead.IPVip, lineSplit[0])
+		case block == "global_defs" && strings.HasPrefix(line, "\tlvs_sync_daemon "):
+			lineSplit := strings.Fields(line)
+			if len(lineSplit) < 2 { // nolint: gomnd
+				return vrrpRead, fmt.Errorf("vrrp config file has bad line %q", line)
+			}
+			vrrpRead.SyncIface = lineSplit[1]
+		case line == "\tstate BACKUP":
+			continue
+		case strings.HasPrefix(line, "\tinterface "):
+			if strings.TrimPrefix(line, "\tinterface ") != ifaceCut {
+				vrrpRead.IfaceForVrrp = strings.TrimPrefix(line, "\tinterface ")
+			}
+		case strings.HasPrefix(line, "\tuse_vmac "):
+			vrrpRead.UseVmac = true
+		case line == "\tvmac_xmit_base":
+			continue
+		case strings.HasPrefix(line, "\tgarp_master_delay "):
+			// 5 is the default value generated without Garp_m_delay
+			if strings.TrimPrefix(line, "\tgarp_master_delay ") != "5" {
+				vrrpRead.GarpMDelay = strings.TrimPrefix(line, "\tgarp_master_delay ")
+			}
+		case strings.HasPrefix(line, "\tgarp_lower_prio_delay "):
+			continue
+		case strings.HasPrefix(line, "\tgarp_master_refresh "):
+			vrrpRead.GarpMasterRefresh = strings.TrimPrefix(line, "\tgarp_master_refresh ")
+		case strings.HasPrefix(line, "\tvirtual_router_id "):
+			vrrpRead.IDVrrp = strings.TrimPrefix(line, "\tvirtual_router_id ")
+		case strings.HasPrefix(line, "\tpriority "):
+			if *isSlave {
+				vrrpRead.PrioSlave = strings.TrimPrefix(line, "\tpriority ")
+			} else {
+				vrrpRead.PrioMaster = strings.TrimPrefix(line, "\tpriority ")
+			}
+		case strings.HasPrefix(line, "\tnotify "):
+			// generated with -notify_cmd
+			continue
+		case strings.HasPrefix(line, "\tadvert_int "):
+			// 1 is the default value generated without Advert_int
+			if strings.TrimPrefix(line, "\tadvert_int ") != "1" {
+				vrrpRead.AdvertInt = strings.TrimPrefix(line, "\tadvert_int ")
+			}
+		case line == "":
+			continue
+		default:
+			return vrrpRead, fmt.Errorf("vrrp config file has unknown line %q", line)
+		}
+	}
+	sort.Strings(vrrpRead.IPVip)
+
+	return vrrpRead, nil
+}
+
+// readIfaceVrrp : read network and vrrp config files of iface on system and fill a ifaceVrrpType.
+// return false if no network and no vrrp config file exist.
+func readIfaceVrrp(iface string) (ifaceVrrpType, bool, error) {
+	ifaceVrrpRead := ifaceVrrpType{
+		Iface: iface,
+	}
+	ifaceExists := checkIfaceExists(ifaceVrrpRead)
+	if ifaceExists {
+		var err error
+		ifaceVrrpRead, err = readIfaceFile(iface)
+		if err != nil {
+			return ifaceVrrpRead, true, err
+		}
+	} else {
+		ifaceVrrpRead.IPVipOnly = true
+	}
+	inventory, err := readInventory()
+	if err != nil {
+		return ifaceVrrpRead, ifaceExists, err
+	}
+	for _, vrrp := range inventory.Vrrps {
+		if vrrp.Name != iface {
+			continue
+		}
+		vrrpRead, err := readVrrpFile(ifaceVrrpType{
+			Iface:     iface,
+			VrrpGroup: vrrp.VrrpGroup,
+			IDVrrp:    vrrp.IDVrrp,
+		})
+		if err != nil {
+			return ifaceVrrpRead, true, err
+		}
+		ifaceVrrpRead.UseVmac = vrrpRead.UseVmac
+		ifaceVrrpRead.PrioMaster = vrrpRead.PrioMaster
+		ifaceVrrpRead.PrioSlave = vrrpRead.PrioSlave
+		ifaceVrrpRead.VrrpGroup = vrrpRead.VrrpGroup
+		ifaceVrrpRead.IfaceForVrrp = vrrpRead.IfaceForVrrp
+		ifaceVrrpRead.IDVrrp = vrrpRead.IDVrrp
+		ifaceVrrpRead.AuthType = vrrpRead.AuthType
+		ifaceVrrpRead.AuthPass = vrrpRead.AuthPass
+		ifaceVrrpRead.SyncIface = vrrpRead.SyncIface
+		ifaceVrrpRead.GarpMDelay = vrrpRead.GarpMDelay
+		ifaceVrrpRead.GarpMasterRefresh = vrrpRead.GarpMasterRefresh
+		ifaceVrrpRead.AdvertInt = vrrpRead.AdvertInt
+		ifaceVrrpRead.IPVip = vrrpRead.IPVip
+		ifaceVrrpRead.TrackScript = vrrpRead.TrackScript
+
+		return ifaceVrrpRead, true, nil
+	}
+
+	return ifaceVrrpRead, ifaceExists, nil
+}
+
+// diffIfaceVrrpFiles : unified diff between generated and on disk network and vrrp config files.
+func diffIfaceVrrpFiles(ifaceVrrp ifaceVrrpType) (diffType, error) {
+	var diff diffType
+	if !ifaceVrrp.IPVipOnly {
+		ifaceIn := generateIfaceFile(ifaceVrrp, true)
+		ifaceRead, err := readFileIfExists(strings.Join([]string{ifaceConfDir(), ifaceVrrp.Iface}, ""))
+		if err != nil {
+			return diff, err
+		}
+		diff.Iface = unifiedDiff(ifaceVrrp.Iface, ifaceIn, ifaceRead)
+	}
+	if len(ifaceVrrp.IPVip) != 0 {
+		vrrpIn, err := generateVrrpFile(ifaceVrrp, true)
+		if err != nil {
+			return diff, err
+		}
+		vrrpRead, err := readFileIfExists(strings.Join([]string{
+			keepalivedConfDir(),
+			ifaceVrrp.VrrpGroup, "/", ifaceVrrp.Iface, "_", ifaceVrrp.IDVrrp, ".conf",
+		}, ""))
+		if err != nil {
+			return diff, err
+		}
+		diff.Vrrp = unifiedDiff(strings.Join([]string{
+			ifaceVrrp.VrrpGroup, "/", ifaceVrrp.Iface, "_", ifaceVrrp.IDVrrp, ".conf",
+		}, ""), withoutNotifyLine(vrrpIn), withoutNotifyLine(vrrpRead))
+	}
+
+	return diff, nil
+}
+
+// diffVrrpScriptFile : unified diff between generated and on disk vrrp script file.
+func diffVrrpScriptFile(vrrpScript vrrpScriptType) (diffType, error) {
+	var diff diffType
+	scriptRead, err := readFileIfExists(strings.Join([]string{
+		keepalivedConfDir(),
+		"script_", vrrpScript.Name, ".conf",
+	}, ""))
+	if err != nil {
+		return diff, err
+	}
+	diff.VrrpScript = unifiedDiff(strings.Join([]string{"script_", vrrpScript.Name, ".conf"}, ""),
+		generateScriptFile(vrrpScript), scriptRead)
+
+	return diff, nil
+}
+
+// diffVirtualServerFile : unified diff between generated and on disk virtual server file.
+func diffVirtualServerFile(virtualServer virtualServerType) (diffType, error) {
+	var diff diffType
+	virtualServerRead, err := readFileIfExists(strings.Join([]string{
+		keepalivedConfDir(),
+		"virtual_server_", virtualServer.Name, ".conf",
+	}, ""))
+	if err != nil {
+		return diff, err
+	}
+	diff.VirtualServer = unifiedDiff(strings.Join([]string{"virtual_server_", virtualServer.Name, ".conf"}, ""),
+		generateVirtualServerFile(virtualServer), virtualServerRead)
+
+	return diff, nil
+}
+
+// readFileIfExists : read file content, empty if file doesn't exist.
+func readFileIfExists(file string) (string, error) {
+	fileByte, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return string(fileByte), nil
+}
+
+// rootPath : path in -root directory (for run on an alternate tree), path unchanged without -root.
+func rootPath(path string) string {
+	if *rootDir == "" {
+		return path
+	}
+
+	return strings.Join([]string{strings.TrimSuffix(*rootDir, "/"), path}, "")
+}
+
+// ifaceConfDir : directory for iface configuration (-iface_dir in -root).
+func ifaceConfDir() string {
+	return rootPath(*ifaceDir)
+}
+
+// keepalivedConfDir : directory for vrrp configuration (-keepalived_dir in -root).
+func keepalivedConfDir() string {
+	return rootPath(*keepalivedDir)
+}
+
+// ifaceFilePath : path of network config file for iface (without -root, same path on each node).
+func ifaceFilePath(iface string) string {
+	return strings.Join([]string{*ifaceDir, iface}, "")
+}
+
+// vrrpFilePath : path of vrrp config file for iface and Id_vrrp in Vrrp_group directory (without -root).
+func vrrpFilePath(ifaceVrrp ifaceVrrpType) string {
+	return strings.Join([]string{
+		*keepalivedDir,
+		ifaceVrrp.VrrpGroup, "/", ifaceVrrp.Iface, "_", ifaceVrrp.IDVrrp, ".conf",
+	}, "")
+}
+
+// managedDirs : directories managed by lvsnetwork-api (without -root).
+func managedDirs() []string {
+	return []string{*ifaceDir, *keepalivedDir}
+}
+
+// checkManagedPath : check if file is in a directory managed by lvsnetwork-api.
+func checkManagedPath(path string) error {
+	pathClean := filepath.Clean(path)
+	for _, dir := range managedDirs() {
+		if strings.HasPrefix(pathClean, dir) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("file %v isn't in managed directory", path)
+}
+
+// readManagedFile : read file in managed directory, Exists=false if file doesn't exist.
+func readManagedFile(path string) (managedFileType, error) {
+	file := managedFileType{
+		Path: path,
+	}
+	err := checkManagedPath(path)
+	if err != nil {
+		return file, err
+	}
+	contentByte, err := ioutil.ReadFile(rootPath(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return file, nil
+		}
+
+		return file, err
+	}
+	file.Exists = true
+	file.Content = string(contentByte)
+
+	return file, nil
+}
+
+// writeManagedFile : write file in managed directory (with parent directory) as read by readManagedFile(),
+// remove it if Exists=false.
+func writeManagedFile(file managedFileType) error {
+	err := checkManagedPath(file.Path)
+	if err != nil {
+		return err
+	}
+	if !file.Exists {
+		err := os.Remove(rootPath(file.Path))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	}
+	err = os.MkdirAll(filepath.Dir(rootPath(file.Path)), os.FileMode(permissionFileCreated))
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(rootPath(file.Path), []byte(file.Content), 0o644)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// readManagedFiles : read all files in managed directories.
+func readManagedFiles() ([]managedFileType, error) {
+	files := make([]managedFileType, 0)
+	for _, dir := range managedDirs() {
+		err := filepath.Walk(rootPath(dir), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			file, err := readManagedFile(strings.TrimPrefix(path, strings.TrimSuffix(*rootDir, "/")))
+			if err != nil {
+				return err
+			}
+			files = append(files, file)
+
+			return nil
+		})
+		if err != nil {
+			return files, err
+		}
+	}
+
+	return files, nil
+}
+
+// vrrpScriptFilePath : path of vrrp script config file (without -root).
+func vrrpScriptFilePath(vrrpScriptName string) string {
+	return strings.Join([]string{*keepalivedDir, "script_", vrrpScriptName, ".conf"}, "")
+}
+
+// virtualServerFilePath : path of virtual server config file (without -root).
+func virtualServerFilePath(virtualServerName string) string {
+	return strings.Join([]string{*keepalivedDir, "virtual_server_", virtualServerName, ".conf"}, "")
+}
+
+// generateIfaceVrrpFiles : network and vrrp config files as written by addIface() and addVrrp() on this server.
+func generateIfaceVrrpFiles(ifaceVrrp ifaceVrrpType) ([]managedFileType, error) {
+	files := make([]managedFileType, 0)
+	if !ifaceVrrp.IPVipOnly {
+		files = append(files, managedFileType{
+			Exists:  true,
+			Path:    ifaceFilePath(ifaceVrrp.Iface),
+			Content: generateIfaceFile(ifaceVrrp, true),
+		})
+	}
+	if len(ifaceVrrp.IPVip) != 0 {
+		vrrpIn, err := generateVrrpFile(ifaceVrrp, true)
+		if err != nil {
+			return files, err
+		}
+		files = append(files, managedFileType{
+			Exists:  true,
+			Path:    vrrpFilePath(ifaceVrrp),
+			Content: vrrpIn,
+		})
+	}
+
+	return files, nil
+}
+
+// stringInSlice : check if string is in list.
+func stringInSlice(str string, list []string) bool {
+	for _, v := range list {
+		if v == str {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// configReloadable : flags read again in -config on SIGHUP (others need a restart).
+var configReloadable = []string{
+	"htpasswd",
+	"ready_timeout",
+	"reload_cmd",
+	"debug",
+	"keepalived_pid",
+	"keepalived_data",
+	"audit_log",
+	"webhooks",
+}
+
+// commandLineFlags : flags set on command line, they override values of -config.
+var commandLineFlags = make(map[string]bool)
+
+// configMutex : lock for reloadable flags, set on SIGHUP while handlers read them.
+var configMutex = &sync.RWMutex{}
+
+// configHtpasswd : value of -htpasswd.
+func configHtpasswd() string {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+
+	return *htpasswdfile
+}
+
+// configReadyTimeout : value of -ready_timeout.
+func configReadyTimeout() int {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+
+	return *readyTimeout
+}
+
+// configReloadCommand : value of -reload_cmd.
+func configReloadCommand() string {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+
+	return *reloadKeepalivedCommand
+}
+
+// configDebug : value of -debug.
+func configDebug() bool {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+
+	return *debug
+}
+
+// configKeepalivedPid : value of -keepalived_pid.
+func configKeepalivedPid() string {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+
+	return *keepalivedPidFile
+}
+
+// configKeepalivedData : value of -keepalived_data.
+func configKeepalivedData() string {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+
+	return *keepalivedDataFile
+}
+
+// configAuditLog : value of -audit_log.
+func configAuditLog() string {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+
+	return *auditLogFile
+}
+
+// configWebhooks : value of -webhooks.
+func configWebhooks() string {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+
+	return *webhooks
+}
+
+// unquoteConfigValue : value of YAML scalar without quotes and comment.
+func unquoteConfigValue(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case strings.HasPrefix(value, "\""):
+		end := strings.LastIndex(value, "\"")
+		if end == 0 {
+			return "", fmt.Errorf("missing quote in %v", value)
+		}
+
+		return strconv.Unquote(value[:end+1])
+	case strings.HasPrefix(value, "'"):
+		end := strings.LastIndex(value, "'")
+		if end == 0 {
+			return "", fmt.Errorf("missing quote in %v", value)
+		}
+
+		return strings.ReplaceAll(value[1:end], "''", "'"), nil
+	}
+	if comment := strings.Index(value, " #"); comment != -1 {
+		value = value[:comment]
+	}
+
+	return strings.TrimSpace(value), nil
+}
+
+// parseConfigFile : read 'flag: value' lines of YAML config file,
+// a list ('flag:' followed by '- item' lines) is a value separated by comma.
+func parseConfigFile(content string) (map[string]string, error) {
+	config := make(map[string]string)
+	list := ""
+	for i, line := range strings.Split(content, "\n") {
+		lineTrim := strings.TrimSpace(line)
+		if lineTrim == "" || strings.HasPrefix(lineTrim, "#") || lineTrim == "---" {
+			continue
+		}
+		if strings.HasPrefix(lineTrim, "- ") || lineTrim == "-" {
+			if list == "" {
+				return nil, fmt.Errorf("line %v : list item without key", i+1)
+			}
+			item, err := unquoteConfigValue(strings.TrimPrefix(lineTrim, "-"))
+			if err != nil {
+				return nil, fmt.Errorf("line %v : %v", i+1, err) // nolint: errorlint
+			}
+			if config[list] == "" {
+				config[list] = item
+			} else {
+				config[list] = strings.Join([]string{config[list], item}, ",")
+			}
+
+			continue
+		}
+		if line != strings.TrimLeft(line, " \t") {
+			return nil, fmt.Errorf("line %v : nested key not supported", i+1)
+		}
+		keyValue := strings.SplitN(lineTrim, ":", 2)
+		if len(keyValue) != 2 {
+			return nil, fmt.Errorf("line %v : missing ':' after key", i+1)
+		}
+		key := strings.TrimSpace(keyValue[0])
+		if _, ok := config[key]; ok {
+			return nil, fmt.Errorf("line %v : %v already defined", i+1, key)
+		}
+		value, err := unquoteConfigValue(keyValue[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %v : %v", i+1, err) // nolint: errorlint
+		}
+		config[key] = value
+		list = ""
+		if value == "" {
+			list = key
+		}
+	}
+
+	return config, nil
+}
+
+// readConfigFile : read and parse -config.
+func readConfigFile() (map[string]string, error) {
+	configByte, err := ioutil.ReadFile(*configFile)
+	if err != nil {
+		return nil, err
+	}
+	config, err := parseConfigFile(string(configByte))
+	if err != nil {
+		return nil, fmt.Errorf("config file %v : %v", *configFile, err) // nolint: errorlint
+	}
+	for key := range config {
+		if key == "config" || key == "send_notify" || flag.Lookup(key) == nil {
+			return nil, fmt.Errorf("config file %v : unknown parameter %v", *configFile, key)
+		}
+	}
+
+	return config, nil
+}
+
+// loadConfig : set flags with values of -config except flags set on command line.
+func loadConfig() error {
+	flag.Visit(func(f *flag.Flag) {
+		commandLineFlags[f.Name] = true
+	})
+	if *configFile == "" {
+		return nil
+	}
+	config, err := readConfigFile()
+	if err != nil {
+		return err
+	}
+	for key, value := range config {
+		if commandLineFlags[key] {
+			continue
+		}
+		err := flag.Set(key, value)
+		if err != nil {
+			return fmt.Errorf("config file %v : bad value for %v : %v", *configFile, key, err) // nolint: errorlint
+		}
+	}
+
+	return nil
+}
+
+// reloadConfig : read -config again and set the reloadable flags (not set on command line),
+// a reloadable flag removed in -config gets its default value.
+func reloadConfig() error {
+	config, err := readConfigFile()
+	if err != nil {
+		return err
+	}
+	for key, value := range config {
+		if !stringInSlice(key, configReloadable) && flag.Lookup(key).Value.String() != value && !commandLineFlags[key] {
+			log.Printf("config reload : %v changed but need a restart", key)
+		}
+	}
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	for _, key := range configReloadable {
+		if commandLineFlags[key] {
+			continue
+		}
+		value, ok := config[key]
+		if !ok {
+			value = flag.Lookup(key).DefValue
+		}
+		err := flag.Set(key, value)
+		if err != nil {
+			return fmt.Errorf("config file %v : bad value for %v : %v", *configFile, key, err) // nolint: errorlint
+		}
+	}
+
+	return nil
+}
+
+// reloadConfigOnSighup : reload -config on each SIGHUP.
+func reloadConfigOnSighup() {
+	if *configFile == "" {
+		return
+	}
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	go func() {
+		for range sighup {
+			err := reloadConfig()
+			if err != nil {
+				log.Printf("config reload error : %v", err)
+
+				continue
+			}
+			log.Printf("config %v reloaded", *configFile)
+		}
+	}()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+const diffContextLines = 3
+
+type diffOpType struct {
+	kind   string
+	line   string
+	indexA int
+	indexB int
+}
+
+// unifiedDiff : generate unified diff between expected (generated) and actual (on disk) content,
+// empty string if same.
+func unifiedDiff(fileName, expected, actual string) string {
+	if expected == actual {
+		return ""
+	}
+	ops := diffOps(splitDiffLines(expected), splitDiffLines(actual))
+	diff := strings.Join([]string{"--- expected/", fileName, "\n", "+++ actual/", fileName, "\n"}, "")
+	for i := 0; i < len(ops); i++ {
+		if ops[i].kind == " " {
+			continue
+		}
+		// find end of hunk: stop when more than 2*context unchanged lines follow a change
+		lastChange := i
+		for j := i + 1; j < len(ops) && j-lastChange <= 2*diffContextLines; j++ {
+			if ops[j].kind != " " {
+				lastChange = j
+			}
+		}
+		start := i - diffContextLines
+		if start < 0 {
+			start = 0
+		}
+		end := lastChange + diffContextLines + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+		diff = strings.Join([]string{diff, diffHunk(ops[start:end])}, "")
+		i = end - 1
+	}
+
+	return diff
+}
+
+// diffHunk : generate one hunk of unified diff.
+func diffHunk(ops []diffOpType) string {
+	startA := ops[0].indexA + 1
+	startB := ops[0].indexB + 1
+	countA := 0
+	countB := 0
+	hunkLines := ""
+	for _, op := range ops {
+		switch op.kind {
+		case " ":
+			countA++
+			countB++
+		case "-":
+			countA++
+		case "+":
+			countB++
+		}
+		hunkLines = strings.Join([]string{hunkLines, op.kind, op.line}, "")
+		if !strings.HasSuffix(op.line, "\n") {
+			// same marker as GNU diff for last line without newline
+			hunkLines = strings.Join([]string{hunkLines, "\n\\ No newline at end of file\n"}, "")
+		}
+	}
+	if countA == 0 {
+		startA--
+	}
+	if countB == 0 {
+		startB--
+	}
+
+	return strings.Join([]string{
+		"@@ -", strconv.Itoa(startA), ",", strconv.Itoa(countA),
+		" +", strconv.Itoa(startB), ",", strconv.Itoa(countB), " @@\n",
+		hunkLines,
+	}, "")
+}
+
+// diffOps : compute list of operations (' ' same, '-' only in a, '+' only in b) with longest common subsequence.
+func diffOps(linesA, linesB []string) []diffOpType {
+	lcs := make([][]int, len(linesA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(linesB)+1)
+	}
+	for i := len(linesA) - 1; i >= 0; i-- {
+		for j := len(linesB) - 1; j >= 0; j-- {
+			switch {
+			case linesA[i] == linesB[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	ops := make([]diffOpType, 0, len(linesA)+len(linesB))
+	i, j := 0, 0
+	for i < len(linesA) || j < len(linesB) {
+		switch {
+		case i < len(linesA) && j < len(linesB) && linesA[i] == linesB[j]:
+			ops = append(ops, diffOpType{kind: " ", line: linesA[i], indexA: i, indexB: j})
+			i++
+			j++
+		case j == len(linesB) || (i < len(linesA) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOpType{kind: "-", line: linesA[i], indexA: i, indexB: j})
+			i++
+		default:
+			ops = append(ops, diffOpType{kind: "+", line: linesB[j], indexA: i, indexB: j})
+			j++
+		}
+	}
+
+	return ops
+}
+
+// splitDiffLines : split content in lines with their newline (last line without newline if missing in content).
+func splitDiffLines(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
@@ -0,0 +1,811 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// e2eNodeType : master or slave of an e2e test, with its tree (-root), keepalived dump and commands recorded.
+type e2eNodeType struct {
+	slave          bool
+	name           string
+	root           string
+	keepalivedData string
+	executor       *fakeExecutor
+	server         *httptest.Server
+}
+
+// e2eType : master and slaves in process, each request on a node runs with flags of this node.
+type e2eType struct {
+	sync.Mutex
+	dir      string
+	state    string
+	master   *e2eNodeType
+	slave    *e2eNodeType
+	slaves   []*e2eNodeType
+	election bool
+	server   *httptest.Server
+	stop     chan struct{}
+}
+
+// use : set flags and executor of node (a request from master to slave waits the response,
+// so master and slaves never run at the same time).
+func (node *e2eNodeType) use() {
+	*isSlave = node.slave
+	*rootDir = node.root
+	*keepalivedDataFile = node.keepalivedData
+	executor = node.executor
+}
+
+// path : path of file in tree of node.
+func (node *e2eNodeType) path(path string) string {
+	return filepath.Join(node.root, path)
+}
+
+// read : content of file in tree of node, empty if it doesn't exist.
+func (node *e2eNodeType) read(t *testing.T, path string) string {
+	t.Helper()
+	content, err := ioutil.ReadFile(node.path(path))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+
+	return string(content)
+}
+
+// executed : commands recorded on node.
+func (node *e2eNodeType) executed() []string {
+	return node.executor.executed()
+}
+
+// vrrpPrios : priority of each vrrp instance in config files of node.
+func (node *e2eNodeType) vrrpPrios() map[string]int {
+	prios := make(map[string]int)
+	files, _ := filepath.Glob(filepath.Join(node.path(*keepalivedDir), "*", "*.conf"))
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		fields := strings.Fields(string(content))
+		if len(fields) < 2 || fields[0] != "vrrp_instance" {
+			continue
+		}
+		prios[fields[1]] = 0
+		for i := 2; i < len(fields)-1; i++ {
+			if fields[i] == "priority" {
+				prios[fields[1]], _ = strconv.Atoi(fields[i+1])
+
+				break
+			}
+		}
+	}
+
+	return prios
+}
+
+// dumpKeepalived : write keepalived dump of each node with vrrp instances of its files in BACKUP state,
+// with election the node with the highest priority of an instance is in MASTER state.
+func (e2e *e2eType) dumpKeepalived() {
+	e2e.Lock()
+	defer e2e.Unlock()
+	nodes := append([]*e2eNodeType{e2e.master}, e2e.slaves...)
+	prios := make([]map[string]int, len(nodes))
+	for i, node := range nodes {
+		prios[i] = node.vrrpPrios()
+	}
+	for i, node := range nodes {
+		instances := make([]string, 0, len(prios[i]))
+		for instance := range prios[i] {
+			instances = append(instances, instance)
+		}
+		sort.Strings(instances)
+		dump := "------< VRRP Topology >------\n"
+		for _, instance := range instances {
+			state := vrrpStateBackup
+			if e2e.election {
+				state = vrrpStateMaster
+				for j := range nodes {
+					if prio, ok := prios[j][instance]; ok && j != i && prio >= prios[i][instance] {
+						state = vrrpStateBackup
+					}
+				}
+			}
+			dump = strings.Join([]string{dump, " VRRP Instance = ", instance, "\n   State = ", state, "\n"}, "")
+		}
+		_ = ioutil.WriteFile(node.keepalivedData, []byte(dump), 0o644)
+		now := time.Now()
+		_ = os.Chtimes(node.keepalivedData, now, now)
+	}
+}
+
+// elect : keepalived puts in MASTER state the node with the highest priority of each vrrp instance
+// (BACKUP everywhere otherwise, VIPs are never added).
+func (e2e *e2eType) elect() {
+	e2e.Lock()
+	defer e2e.Unlock()
+	e2e.election = true
+}
+
+// newNode : node with its tree in e2e directory.
+func (e2e *e2eType) newNode(t *testing.T, name string, slave bool) *e2eNodeType {
+	t.Helper()
+	node := &e2eNodeType{
+		slave:          slave,
+		name:           name,
+		root:           filepath.Join(e2e.dir, name),
+		keepalivedData: filepath.Join(e2e.dir, strings.Join([]string{name, ".data"}, "")),
+		executor:       newFakeExecutor(),
+	}
+	for _, confDir := range managedDirs() {
+		err := os.MkdirAll(node.path(confDir), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return node
+}
+
+// addSlave : start a slave node added in peers of master.
+func (e2e *e2eType) addSlave(t *testing.T, name string) *e2eNodeType {
+	t.Helper()
+	node := e2e.newNode(t, name, true)
+	slaveRoutes := slaveRouter()
+	node.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		node.use()
+		defer e2e.master.use()
+		slaveRoutes.ServeHTTP(w, r)
+	}))
+	slaveHost, slavePort, err := net.SplitHostPort(node.server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	peers = append(peers, peerType{Name: name, IP: slaveHost, Port: slavePort})
+	e2e.Lock()
+	e2e.slaves = append(e2e.slaves, node)
+	e2e.Unlock()
+
+	return node
+}
+
+// newE2E : start master and slave (stopped by close()) on temporary trees and state directory,
+// with keepalived answering SIGUSR1 (this process) and ifaces up in a temporary /sys/class/net/.
+func newE2E(t *testing.T, ifaces ...string) *e2eType {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "lvsnetwork-api-e2e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e2e := &e2eType{
+		dir:   dir,
+		state: *stateDir,
+		stop:  make(chan struct{}),
+	}
+	*stateDir = filepath.Join(dir, "state")
+	e2e.master = e2e.newNode(t, "master", false)
+	sysClassNetDir = filepath.Join(dir, "net") + "/"
+	for _, iface := range ifaces {
+		err := os.MkdirAll(filepath.Join(sysClassNetDir, iface), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(filepath.Join(sysClassNetDir, iface, "operstate"), []byte("up\n"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	*keepalivedPidFile = filepath.Join(dir, "keepalived.pid")
+	err = ioutil.WriteFile(*keepalivedPidFile, []byte(strconv.Itoa(os.Getpid())), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigusr1 := make(chan os.Signal, 1)
+	signal.Notify(sigusr1, syscall.SIGUSR1)
+	go func() {
+		for {
+			select {
+			case <-sigusr1:
+				e2e.dumpKeepalived()
+			case <-e2e.stop:
+				signal.Stop(sigusr1)
+
+				return
+			}
+		}
+	}()
+	peers = nil
+	e2e.slave = e2e.addSlave(t, "slave")
+	masterRoutes := masterRouter()
+	e2e.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e2e.master.use()
+		masterRoutes.ServeHTTP(w, r)
+	}))
+	e2e.master.use()
+
+	return e2e
+}
+
+// close : stop master, slaves and keepalived, flags back to default values.
+func (e2e *e2eType) close() {
+	e2e.server.Close()
+	for _, slave := range e2e.slaves {
+		slave.server.Close()
+	}
+	close(e2e.stop)
+	testFlags(e2e.state)
+	executor = newFakeExecutor()
+	sysClassNetDir = "/sys/class/net/"
+	os.RemoveAll(e2e.dir)
+}
+
+// request : send request to master with body in json (nil for without body), return status code and body.
+func (e2e *e2eType) request(t *testing.T, url string, body interface{}) (int, string) {
+	t.Helper()
+	method := http.MethodGet
+	reqBody := new(bytes.Buffer)
+	if body != nil {
+		method = http.MethodPost
+		err := json.NewEncoder(reqBody).Encode(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, e2e.server.URL+url, reqBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.StatusCode, string(respBody)
+}
+
+// reset : forget commands recorded on master and slaves.
+func (e2e *e2eType) reset() {
+	e2e.master.executor.reset()
+	for _, slave := range e2e.slaves {
+		slave.executor.reset()
+	}
+}
+
+func containsCommand(commands []string, command string) bool {
+	for _, executed := range commands {
+		if executed == command {
+			return true
+		}
+	}
+
+	return false
+}
+
+func countCommand(commands []string, command string) int {
+	count := 0
+	for _, executed := range commands {
+		if executed == command {
+			count++
+		}
+	}
+
+	return count
+}
+
+const e2eReload = "/etc/init.d/keepalived-vrrp reload"
+
+func e2eIfaceVrrp() ifaceVrrpType {
+	return ifaceVrrpType{
+		Iface:      "eth1",
+		IPMaster:   "10.0.0.2",
+		IPSlave:    "10.0.0.3",
+		Mask:       "24",
+		VrrpGroup:  "VG_1",
+		IDVrrp:     "10",
+		PrioMaster: "150",
+		PrioSlave:  "100",
+		AuthType:   "PASS",
+		AuthPass:   "secret",
+		IPVip:      []string{"10.0.0.1"},
+	}
+}
+
+func TestE2EAddIfaceVrrp(t *testing.T) {
+	e2e := newE2E(t, "eth1")
+	defer e2e.close()
+	statusCode, body := e2e.request(t, "/add_iface_vrrp/eth1/", e2eIfaceVrrp())
+	if statusCode != http.StatusOK {
+		t.Fatalf("add_iface_vrrp : %v %v", statusCode, body)
+	}
+	for _, test := range []struct {
+		node *e2eNodeType
+		ip   string
+		prio string
+		name string
+	}{
+		{node: e2e.master, ip: "10.0.0.2", prio: "150", name: "master"},
+		{node: e2e.slave, ip: "10.0.0.3", prio: "100", name: "slave"},
+	} {
+		iface := test.node.read(t, "/etc/network/interfaces.d/eth1")
+		if !strings.Contains(iface, strings.Join([]string{"\taddress ", test.ip, "/24\n"}, "")) {
+			t.Errorf("iface file on %v without address %v :\n%v", test.name, test.ip, iface)
+		}
+		vrrp := test.node.read(t, "/etc/keepalived/keepalived-vrrp.d/VG_1/eth1_10.conf")
+		if !strings.HasPrefix(vrrp, "vrrp_instance ") ||
+			!strings.Contains(vrrp, strings.Join([]string{"\tpriority ", test.prio, "\n"}, "")) ||
+			!strings.Contains(vrrp, "\t\t10.0.0.1 dev eth1\n") {
+			t.Errorf("vrrp file on %v :\n%v", test.name, vrrp)
+		}
+		syncGroup := test.node.read(t, "/etc/keepalived/keepalived-vrrp.d/VG_1/vrrp_sync_group")
+		if !strings.HasPrefix(syncGroup, "vrrp_sync_group VG_1 {\n\tgroup {\n") {
+			t.Errorf("vrrp_sync_group file on %v :\n%v", test.name, syncGroup)
+		}
+		commands := test.node.executed()
+		for _, command := range []string{"ifup eth1", "ifquery eth1 --state", e2eReload} {
+			if !containsCommand(commands, command) {
+				t.Errorf("command %q not executed on %v : %q", command, test.name, commands)
+			}
+		}
+	}
+	if !containsCommand(e2e.master.executed(), "ping -c1 -t1 10.0.0.3") {
+		t.Errorf("master doesn't ping slave : %q", e2e.master.executed())
+	}
+
+	// same configuration : nothing to do
+	e2e.reset()
+	statusCode, body = e2e.request(t, "/check_iface_vrrp/eth1/", e2eIfaceVrrp())
+	if statusCode != http.StatusOK {
+		t.Errorf("check_iface_vrrp after add : %v %v", statusCode, body)
+	}
+	if containsCommand(e2e.master.executed(), e2eReload) || containsCommand(e2e.slave.executed(), e2eReload) {
+		t.Errorf("reload on check : %q %q", e2e.master.executed(), e2e.slave.executed())
+	}
+}
+
+func TestE2EChangeVrrpScriptRollback(t *testing.T) {
+	e2e := newE2E(t)
+	defer e2e.close()
+	vrrpScript := vrrpScriptType{
+		Name:     "chk_http",
+		Script:   "/usr/local/bin/check_http",
+		Interval: 2,
+		Fall:     2,
+		Rise:     2,
+		Weight:   10,
+	}
+	statusCode, body := e2e.request(t, "/add_vrrp_script/chk_http/", vrrpScript)
+	if statusCode != http.StatusOK {
+		t.Fatalf("add_vrrp_script : %v %v", statusCode, body)
+	}
+	scriptPath := "/etc/keepalived/keepalived-vrrp.d/script_chk_http.conf"
+	masterBefore := e2e.master.read(t, scriptPath)
+	if masterBefore != generateScriptFile(vrrpScript) || e2e.slave.read(t, scriptPath) != masterBefore {
+		t.Fatalf("vrrp_script files :\n%v\n%v", masterBefore, e2e.slave.read(t, scriptPath))
+	}
+
+	// reload fails on slave : master file restored and reloaded
+	e2e.reset()
+	e2e.slave.executor.fail(e2eReload)
+	vrrpScript.Interval = 5
+	statusCode, body = e2e.request(t, "/change_vrrp_script/chk_http/", vrrpScript)
+	if statusCode != http.StatusInternalServerError {
+		t.Fatalf("change_vrrp_script with reload failed on slave : %v %v", statusCode, body)
+	}
+	if e2e.master.read(t, scriptPath) != masterBefore {
+		t.Errorf("vrrp_script on master not restored :\n%v", e2e.master.read(t, scriptPath))
+	}
+	if e2e.slave.read(t, scriptPath) != masterBefore {
+		t.Errorf("vrrp_script on slave not restored :\n%v", e2e.slave.read(t, scriptPath))
+	}
+	if count := countCommand(e2e.master.executed(), e2eReload); count != 2 {
+		t.Errorf("master reloaded %v times instead of 2 (change and rollback) : %q", count, e2e.master.executed())
+	}
+}
+
+func TestE2ERealServer(t *testing.T) {
+	e2e := newE2E(t)
+	defer e2e.close()
+	virtualServer := virtualServerType{
+		Name:     "web",
+		VIP:      "10.0.0.1",
+		Port:     80,
+		Protocol: "TCP",
+		LbAlgo:   "rr",
+		LbKind:   "NAT",
+		RealServers: []realServerType{{
+			IP:     "10.0.1.1",
+			Port:   80,
+			Weight: 1,
+		}},
+	}
+	statusCode, body := e2e.request(t, "/add_virtual_server/web/", virtualServer)
+	if statusCode != http.StatusOK {
+		t.Fatalf("add_virtual_server : %v %v", statusCode, body)
+	}
+	virtualServerPath := "/etc/keepalived/keepalived-vrrp.d/virtual_server_web.conf"
+	realServer := realServerType{IP: "10.0.1.2", Port: 80, Weight: 1}
+
+	// dry run : plan with files and nothing written
+	e2e.reset()
+	statusCode, body = e2e.request(t, "/add_real_server/web/?dry_run=true", realServer)
+	if statusCode != http.StatusOK || !strings.Contains(body, "write virtual_server on slave") {
+		t.Fatalf("add_real_server in dry run : %v %v", statusCode, body)
+	}
+	if strings.Contains(e2e.slave.read(t, virtualServerPath), "10.0.1.2") ||
+		len(e2e.master.executed()) != 0 || len(e2e.slave.executed()) != 0 {
+		t.Errorf("add_real_server in dry run changes slave : %q %q", e2e.master.executed(), e2e.slave.executed())
+	}
+
+	statusCode, body = e2e.request(t, "/add_real_server/web/", realServer)
+	if statusCode != http.StatusOK {
+		t.Fatalf("add_real_server : %v %v", statusCode, body)
+	}
+	virtualServer.RealServers = append(virtualServer.RealServers, realServer)
+	for name, node := range map[string]*e2eNodeType{"master": e2e.master, "slave": e2e.slave} {
+		if content := node.read(t, virtualServerPath); content != generateVirtualServerFile(virtualServer) {
+			t.Errorf("virtual_server on %v after add_real_server :\n%v", name, content)
+		}
+		if !containsCommand(node.executed(), e2eReload) {
+			t.Errorf("no reload on %v : %q", name, node.executed())
+		}
+	}
+
+	statusCode, body = e2e.request(t, "/drain_real_server/web/", realServer)
+	if statusCode != http.StatusOK {
+		t.Fatalf("drain_real_server : %v %v", statusCode, body)
+	}
+	virtualServer.RealServers[1].Weight = 0
+	for name, node := range map[string]*e2eNodeType{"master": e2e.master, "slave": e2e.slave} {
+		if content := node.read(t, virtualServerPath); content != generateVirtualServerFile(virtualServer) {
+			t.Errorf("virtual_server on %v after drain_real_server :\n%v", name, content)
+		}
+	}
+
+	for _, url := range []string{"/drain_real_server/web/", "/remove_real_server/web/"} {
+		statusCode, body = e2e.request(t, url, realServerType{IP: "10.0.1.9", Port: 80})
+		if statusCode != http.StatusNotFound {
+			t.Errorf("%v with real_server not found : %v %v", url, statusCode, body)
+		}
+	}
+	statusCode, body = e2e.request(t, "/add_real_server/unknown/", realServer)
+	if statusCode != http.StatusNotFound {
+		t.Errorf("add_real_server in virtual_server not found : %v %v", statusCode, body)
+	}
+
+	statusCode, body = e2e.request(t, "/remove_real_server/web/", realServer)
+	if statusCode != http.StatusOK {
+		t.Fatalf("remove_real_server : %v %v", statusCode, body)
+	}
+	virtualServer.RealServers = virtualServer.RealServers[:1]
+	for name, node := range map[string]*e2eNodeType{"master": e2e.master, "slave": e2e.slave} {
+		if content := node.read(t, virtualServerPath); content != generateVirtualServerFile(virtualServer) {
+			t.Errorf("virtual_server on %v after remove_real_server :\n%v", name, content)
+		}
+	}
+
+	// dry run of remove : plan with file removed and nothing removed
+	e2e.reset()
+	statusCode, body = e2e.request(t, "/remove_virtual_server/web/?dry_run=true", virtualServer)
+	if statusCode != http.StatusOK || !strings.Contains(body, "remove virtual_server on slave") {
+		t.Fatalf("remove_virtual_server in dry run : %v %v", statusCode, body)
+	}
+	if e2e.slave.read(t, virtualServerPath) == "" || len(e2e.slave.executed()) != 0 {
+		t.Errorf("remove_virtual_server in dry run changes slave : %q", e2e.slave.executed())
+	}
+
+	statusCode, body = e2e.request(t, "/remove_virtual_server/web/", virtualServer)
+	if statusCode != http.StatusOK {
+		t.Fatalf("remove_virtual_server : %v %v", statusCode, body)
+	}
+	for name, node := range map[string]*e2eNodeType{"master": e2e.master, "slave": e2e.slave} {
+		if content := node.read(t, virtualServerPath); content != "" {
+			t.Errorf("virtual_server on %v after remove_virtual_server :\n%v", name, content)
+		}
+	}
+}
+
+func TestE2ESnapshotRestore(t *testing.T) {
+	e2e := newE2E(t, "eth1", "eth2")
+	defer e2e.close()
+	statusCode, body := e2e.request(t, "/add_iface_vrrp/eth1/", e2eIfaceVrrp())
+	if statusCode != http.StatusOK {
+		t.Fatalf("add_iface_vrrp : %v %v", statusCode, body)
+	}
+	statusCode, body = e2e.request(t, "/snapshot/", nil)
+	if statusCode != http.StatusOK {
+		t.Fatalf("snapshot : %v %v", statusCode, body)
+	}
+	var snapshotNodes snapshotType
+	err := json.Unmarshal([]byte(body), &snapshotNodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ifacePath := "/etc/network/interfaces.d/eth1"
+	ifaceBefore := e2e.slave.read(t, ifacePath)
+
+	// after snapshot : post-up added on eth1 and new iface_vrrp eth2
+	ifaceVrrp := e2eIfaceVrrp()
+	ifaceVrrp.PostUp = []string{"ip route add 10.1.0.0/24 via 10.0.0.254"}
+	statusCode, body = e2e.request(t, "/change_iface_vrrp/eth1/", ifaceVrrp)
+	if statusCode != http.StatusOK {
+		t.Fatalf("change_iface_vrrp : %v %v", statusCode, body)
+	}
+	ifaceVrrp2 := e2eIfaceVrrp()
+	ifaceVrrp2.Iface = "eth2"
+	ifaceVrrp2.IPMaster = "10.0.2.2"
+	ifaceVrrp2.IPSlave = "10.0.2.3"
+	ifaceVrrp2.IDVrrp = "20"
+	ifaceVrrp2.IPVip = []string{"10.0.2.1"}
+	statusCode, body = e2e.request(t, "/add_iface_vrrp/eth2/", ifaceVrrp2)
+	if statusCode != http.StatusOK {
+		t.Fatalf("add_iface_vrrp eth2 : %v %v", statusCode, body)
+	}
+	ifaceVrrp2Path := "/etc/keepalived/keepalived-vrrp.d/VG_1/eth2_20.conf"
+
+	// dry run : files of eth2 removed in plan and nothing done
+	e2e.reset()
+	statusCode, body = e2e.request(t, "/restore/?dry_run=true", snapshotNodes)
+	if statusCode != http.StatusOK || !strings.Contains(body, ifaceVrrp2Path) {
+		t.Fatalf("restore in dry run : %v %v", statusCode, body)
+	}
+	if e2e.slave.read(t, ifaceVrrp2Path) == "" || len(e2e.slave.executed()) != 0 {
+		t.Errorf("restore in dry run changes slave : %q", e2e.slave.executed())
+	}
+
+	e2e.reset()
+	statusCode, body = e2e.request(t, "/restore/", snapshotNodes)
+	if statusCode != http.StatusOK {
+		t.Fatalf("restore : %v %v", statusCode, body)
+	}
+	for name, node := range map[string]*e2eNodeType{"master": e2e.master, "slave": e2e.slave} {
+		for _, path := range []string{"/etc/network/interfaces.d/eth2", ifaceVrrp2Path} {
+			if content := node.read(t, path); content != "" {
+				t.Errorf("%v not in snapshot on %v not removed :\n%v", path, name, content)
+			}
+		}
+		commands := node.executed()
+		for _, command := range []string{
+			"ifdown eth2 --force", "ip route del 10.1.0.0/24 via 10.0.0.254", "ifdown eth1 --force", "ifup eth1", e2eReload,
+		} {
+			if !containsCommand(commands, command) {
+				t.Errorf("command %q not executed on %v : %q", command, name, commands)
+			}
+		}
+	}
+	if content := e2e.slave.read(t, ifacePath); content != ifaceBefore {
+		t.Errorf("iface on slave not restored :\n%v", content)
+	}
+}
+
+func TestE2EIfaceVrrpRollback(t *testing.T) {
+	e2e := newE2E(t, "eth1")
+	defer e2e.close()
+	ifacePath := "/etc/network/interfaces.d/eth1"
+	vrrpPath := "/etc/keepalived/keepalived-vrrp.d/VG_1/eth1_10.conf"
+
+	// ifup fails on slave : add reverted on master and slave
+	e2e.slave.executor.fail("ifup eth1")
+	statusCode, body := e2e.request(t, "/add_iface_vrrp/eth1/", e2eIfaceVrrp())
+	if statusCode != http.StatusInternalServerError {
+		t.Fatalf("add_iface_vrrp with ifup failed on slave : %v %v", statusCode, body)
+	}
+	for name, node := range map[string]*e2eNodeType{"master": e2e.master, "slave": e2e.slave} {
+		for _, path := range []string{ifacePath, vrrpPath} {
+			if content := node.read(t, path); content != "" {
+				t.Errorf("%v on %v not removed by rollback of add :\n%v", path, name, content)
+			}
+		}
+	}
+	if !containsCommand(e2e.master.executed(), "ifdown eth1 --force") {
+		t.Errorf("iface not down on master by rollback of add : %q", e2e.master.executed())
+	}
+
+	e2e.reset()
+	statusCode, body = e2e.request(t, "/add_iface_vrrp/eth1/", e2eIfaceVrrp())
+	if statusCode != http.StatusOK {
+		t.Fatalf("add_iface_vrrp : %v %v", statusCode, body)
+	}
+	before := make(map[string]map[string]string)
+	for name, node := range map[string]*e2eNodeType{"master": e2e.master, "slave": e2e.slave} {
+		before[name] = map[string]string{
+			ifacePath: node.read(t, ifacePath),
+			vrrpPath:  node.read(t, vrrpPath),
+		}
+	}
+
+	// reload fails on slave : change of iface and vrrp reverted on master and slave
+	e2e.reset()
+	e2e.slave.executor.fail(e2eReload)
+	ifaceVrrp := e2eIfaceVrrp()
+	ifaceVrrp.PostUp = []string{"ip route add 10.1.0.0/24 via 10.0.0.254"}
+	ifaceVrrp.PrioSlave = "90"
+	ifaceVrrp.IPVip = []string{"10.0.0.1", "10.0.0.6"}
+	statusCode, body = e2e.request(t, "/change_iface_vrrp/eth1/", ifaceVrrp)
+	if statusCode != http.StatusInternalServerError {
+		t.Fatalf("change_iface_vrrp with reload failed on slave : %v %v", statusCode, body)
+	}
+	if !containsCommand(e2e.master.executed(), "ip route add 10.1.0.0/24 via 10.0.0.254") {
+		t.Errorf("change not applied on master before failure on slave : %q", e2e.master.executed())
+	}
+	for name, node := range map[string]*e2eNodeType{"master": e2e.master, "slave": e2e.slave} {
+		for _, path := range []string{ifacePath, vrrpPath} {
+			if content := node.read(t, path); content != before[name][path] {
+				t.Errorf("%v on %v not restored by rollback of change :\n%v", path, name, content)
+			}
+		}
+	}
+}
+
+func TestE2ERemoveIfaceVrrpPeers(t *testing.T) {
+	e2e := newE2E(t, "eth1")
+	defer e2e.close()
+	slave2 := e2e.addSlave(t, "slave2")
+	statusCode, body := e2e.request(t, "/add_iface_vrrp/eth1/", e2eIfaceVrrp())
+	if statusCode != http.StatusOK {
+		t.Fatalf("add_iface_vrrp : %v %v", statusCode, body)
+	}
+	ifacePath := "/etc/network/interfaces.d/eth1"
+	vrrpPath := "/etc/keepalived/keepalived-vrrp.d/VG_1/eth1_10.conf"
+	nodes := map[string]*e2eNodeType{"master": e2e.master, "slave": e2e.slave, "slave2": slave2}
+	for name, node := range nodes {
+		if node.read(t, ifacePath) == "" || node.read(t, vrrpPath) == "" {
+			t.Fatalf("iface_vrrp not added on %v", name)
+		}
+	}
+
+	// iface_vrrp already missing on slave : removed on other nodes
+	for _, path := range []string{ifacePath, vrrpPath} {
+		err := os.Remove(e2e.slave.path(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	e2e.reset()
+	statusCode, body = e2e.request(t, "/remove_iface_vrrp/eth1/", e2eIfaceVrrp())
+	if statusCode != http.StatusOK {
+		t.Fatalf("remove_iface_vrrp : %v %v", statusCode, body)
+	}
+	for name, node := range nodes {
+		for _, path := range []string{ifacePath, vrrpPath} {
+			if content := node.read(t, path); content != "" {
+				t.Errorf("%v on %v not removed :\n%v", path, name, content)
+			}
+		}
+	}
+	for name, node := range map[string]*e2eNodeType{"master": e2e.master, "slave2": slave2} {
+		if !containsCommand(node.executed(), "ifdown eth1 --force") {
+			t.Errorf("iface not down on %v : %q", name, node.executed())
+		}
+	}
+	if containsCommand(e2e.slave.executed(), "ifdown eth1 --force") {
+		t.Errorf("iface down on slave without iface : %q", e2e.slave.executed())
+	}
+}
+
+func TestE2EMaintenance(t *testing.T) {
+	e2e := newE2E(t, "eth1")
+	defer e2e.close()
+	statusCode, body := e2e.request(t, "/add_iface_vrrp/eth1/", e2eIfaceVrrp())
+	if statusCode != http.StatusOK {
+		t.Fatalf("add_iface_vrrp : %v %v", statusCode, body)
+	}
+	e2e.elect()
+	vrrpPath := "/etc/keepalived/keepalived-vrrp.d/VG_1/eth1_10.conf"
+	masterBefore := e2e.master.read(t, vrrpPath)
+	slaveBefore := e2e.slave.read(t, vrrpPath)
+
+	statusCode, body = e2e.request(t, "/enter_maintenance/master/", nil)
+	if statusCode != http.StatusOK {
+		t.Fatalf("enter_maintenance : %v %v", statusCode, body)
+	}
+	if vrrp := e2e.master.read(t, vrrpPath); !strings.Contains(vrrp, "\tpriority 1\n") {
+		t.Errorf("vrrp on master in maintenance without lowest priority :\n%v", vrrp)
+	}
+	if e2e.slave.read(t, vrrpPath) != slaveBefore {
+		t.Errorf("vrrp on slave changed by maintenance of master :\n%v", e2e.slave.read(t, vrrpPath))
+	}
+	statusCode, body = e2e.request(t, "/state_vrrp/", nil)
+	if statusCode != http.StatusOK || !strings.Contains(body, `"maintenance":true`) {
+		t.Errorf("state_vrrp with master in maintenance : %v %v", statusCode, body)
+	}
+	statusCode, body = e2e.request(t, "/change_iface_vrrp/eth1/", e2eIfaceVrrp())
+	if statusCode != http.StatusConflict {
+		t.Errorf("change_iface_vrrp with master in maintenance : %v %v", statusCode, body)
+	}
+
+	statusCode, body = e2e.request(t, "/exit_maintenance/master/", nil)
+	if statusCode != http.StatusOK {
+		t.Fatalf("exit_maintenance : %v %v", statusCode, body)
+	}
+	if e2e.master.read(t, vrrpPath) != masterBefore {
+		t.Errorf("vrrp on master not restored by exit_maintenance :\n%v", e2e.master.read(t, vrrpPath))
+	}
+
+	// maintenance file not readable : node reported in maintenance and exit still possible
+	err := os.MkdirAll(maintenanceDir(), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(maintenanceFilePath("slave"), []byte("{"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statusCode, body = e2e.request(t, "/maintenance/", nil)
+	if statusCode != http.StatusOK || !strings.Contains(body, `"node":"slave"`) || !strings.Contains(body, `"error":`) {
+		t.Errorf("maintenance with file not readable : %v %v", statusCode, body)
+	}
+	statusCode, body = e2e.request(t, "/change_iface_vrrp/eth1/", e2eIfaceVrrp())
+	if statusCode != http.StatusConflict {
+		t.Errorf("change_iface_vrrp with maintenance file not readable : %v %v", statusCode, body)
+	}
+	statusCode, body = e2e.request(t, "/exit_maintenance/slave/", nil)
+	if statusCode != http.StatusOK {
+		t.Fatalf("exit_maintenance with file not readable : %v %v", statusCode, body)
+	}
+	if e2e.master.read(t, vrrpPath) != masterBefore || e2e.slave.read(t, vrrpPath) != slaveBefore {
+		t.Errorf("vrrp changed by exit_maintenance with file not readable :\n%v\n%v",
+			e2e.master.read(t, vrrpPath), e2e.slave.read(t, vrrpPath))
+	}
+}
+
+func TestE2EFailover(t *testing.T) {
+	e2e := newE2E(t, "eth1")
+	defer e2e.close()
+	statusCode, body := e2e.request(t, "/add_iface_vrrp/eth1/", e2eIfaceVrrp())
+	if statusCode != http.StatusOK {
+		t.Fatalf("add_iface_vrrp : %v %v", statusCode, body)
+	}
+	e2e.elect()
+	vrrpPath := "/etc/keepalived/keepalived-vrrp.d/VG_1/eth1_10.conf"
+	masterBefore := e2e.master.read(t, vrrpPath)
+	slaveBefore := e2e.slave.read(t, vrrpPath)
+
+	statusCode, body = e2e.request(t, "/failover/VG_1/", failoverType{})
+	if statusCode != http.StatusOK {
+		t.Fatalf("failover : %v %v", statusCode, body)
+	}
+	for _, test := range []struct {
+		node *e2eNodeType
+		prio string
+		name string
+	}{
+		{node: e2e.master, prio: "100", name: "master"},
+		{node: e2e.slave, prio: "150", name: "slave"},
+	} {
+		if vrrp := test.node.read(t, vrrpPath); !strings.Contains(vrrp, strings.Join([]string{"\tpriority ", test.prio, "\n"}, "")) {
+			t.Errorf("vrrp on %v after failover without priority %v :\n%v", test.name, test.prio, vrrp)
+		}
+	}
+	statusCode, body = e2e.request(t, "/failover/VG_1/", failoverType{})
+	if statusCode != http.StatusBadRequest {
+		t.Errorf("failover already done : %v %v", statusCode, body)
+	}
+
+	statusCode, body = e2e.request(t, "/failover/VG_1/", failoverType{Restore: true})
+	if statusCode != http.StatusOK {
+		t.Fatalf("restore of failover : %v %v", statusCode, body)
+	}
+	if e2e.master.read(t, vrrpPath) != masterBefore || e2e.slave.read(t, vrrpPath) != slaveBefore {
+		t.Errorf("vrrp not restored by restore of failover :\n%v\n%v",
+			e2e.master.read(t, vrrpPath), e2e.slave.read(t, vrrpPath))
+	}
+}
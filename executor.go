@@ -0,0 +1,20 @@
+package main
+
+import (
+	"os/exec"
+)
+
+// executorType : run of external commands (ifup, ifdown, ifquery, ip, ping, keepalived, reload_cmd and post-up).
+type executorType interface {
+	// run : execute command and return its output (stdout and stderr).
+	run(name string, args ...string) ([]byte, error)
+}
+
+// execExecutor : executor with exec.Command.
+type execExecutor struct{}
+
+func (execExecutor) run(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).CombinedOutput()
+}
+
+var executor executorType = execExecutor{}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// fakeExecutor : executor which only records commands,
+// with output of commands needed at start (ifquery --help and keepalived -v) and commands which fail.
+type fakeExecutor struct {
+	sync.Mutex
+	commands []string
+	outputs  map[string]string
+	failures map[string]bool
+}
+
+func newFakeExecutor() *fakeExecutor {
+	return &fakeExecutor{
+		commands: make([]string, 0),
+		outputs: map[string]string{
+			"ifquery --help": "Usage: ifquery [options] <ifaces...>\n\t--state\tshow the state of interfaces\n",
+			"keepalived -v":  "Keepalived v2.0.20 (fake)\n",
+		},
+		failures: make(map[string]bool),
+	}
+}
+
+func (fe *fakeExecutor) run(name string, args ...string) ([]byte, error) {
+	command := strings.Join(append([]string{name}, args...), " ")
+	fe.Lock()
+	defer fe.Unlock()
+	fe.commands = append(fe.commands, command)
+	if fe.failures[command] {
+		return []byte(command), fmt.Errorf("%v failed", command)
+	}
+
+	return []byte(fe.outputs[command]), nil
+}
+
+// executed : commands recorded in order.
+func (fe *fakeExecutor) executed() []string {
+	fe.Lock()
+	defer fe.Unlock()
+
+	return append([]string{}, fe.commands...)
+}
+
+// reset : forget commands recorded.
+func (fe *fakeExecutor) reset() {
+	fe.Lock()
+	defer fe.Unlock()
+	fe.commands = make([]string, 0)
+	fe.failures = make(map[string]bool)
+}
+
+// fail : command returns an error until reset.
+func (fe *fakeExecutor) fail(command string) {
+	fe.Lock()
+	defer fe.Unlock()
+	fe.failures[command] = true
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	auth "github.com/abbot/go-http-auth"
+	"github.com/gorilla/mux"
+)
+
+// failoverDir : directory for save priorities before failover.
+func failoverDir() string {
+	return filepath.Join(*stateDir, "failover")
+}
+
+// failoverFilePath : file with priorities saved before failover of vrrp_sync_group.
+func failoverFilePath(vrrpGroup string) string {
+	return filepath.Join(failoverDir(), strings.Join([]string{vrrpGroup, ".json"}, ""))
+}
+
+// readFailoverState : read priorities saved by last failover of vrrp_sync_group, false if no failover to restore.
+func readFailoverState(vrrpGroup string) (failoverStateType, bool, error) {
+	var failoverState failoverStateType
+	failoverByte, err := ioutil.ReadFile(failoverFilePath(vrrpGroup))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return failoverState, false, nil
+		}
+
+		return failoverState, false, err
+	}
+	err = json.Unmarshal(failoverByte, &failoverState)
+	if err != nil {
+		return failoverState, true, err
+	}
+
+	return failoverState, true, nil
+}
+
+// saveFailoverState : write priorities before failover of vrrp_sync_group in state directory.
+func saveFailoverState(vrrpGroup string, failoverState failoverStateType) error {
+	err := os.MkdirAll(failoverDir(), os.FileMode(permissionFileCreated))
+	if err != nil {
+		return err
+	}
+	failoverByte, err := json.Marshal(failoverState)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(failoverFilePath(vrrpGroup), failoverByte, 0o644)
+}
+
+// removeFailoverState : remove priorities saved by failover of vrrp_sync_group.
+func removeFailoverState(vrrpGroup string) error {
+	err := os.Remove(failoverFilePath(vrrpGroup))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// vrrpStateKey : unique key of vrrp instance (path of vrrp config file).
+func vrrpStateKey(vrrpState vrrpStateType) string {
+	return vrrpFilePath(ifaceVrrpType{
+		Iface:     vrrpState.Iface,
+		VrrpGroup: vrrpState.VrrpGroup,
+		IDVrrp:    vrrpState.IDVrrp,
+	})
+}
+
+// newFailoverState : find node MASTER of instances and target node, save current priorities of each node,
+// return a message if failover isn't possible.
+func newFailoverState(instances []vrrpStateType, nodes []nodeOpsType, target string) (failoverStateType, string) {
+	failoverState := failoverStateType{
+		Prios: make(map[string]map[string]string),
+	}
+	for _, node := range nodes {
+		failoverState.Prios[node.name] = make(map[string]string)
+	}
+	for _, instance := range instances {
+		source := ""
+		for _, node := range nodes {
+			nodeState, ok := instance.Nodes[node.name]
+			if !ok {
+				return failoverState, fmt.Sprintf("vrrp instance %v not on %v", instance.Instance, node.name)
+			}
+			if nodeState.State == vrrpStateMaster {
+				source = node.name
+			}
+			failoverState.Prios[node.name][vrrpStateKey(instance)] = nodeState.Prio
+		}
+		if source == "" {
+			return failoverState, fmt.Sprintf("no node MASTER for vrrp instance %v", instance.Instance)
+		}
+		if failoverState.Source != "" && failoverState.Source != source {
+			return failoverState, "vrrp instances haven't the same node MASTER"
+		}
+		failoverState.Source = source
+	}
+	switch {
+	case target != "":
+		if _, ok := failoverState.Prios[target]; !ok {
+			return failoverState, fmt.Sprintf("unknown node %v", target)
+		}
+		failoverState.Target = target
+	case len(nodes) == 2:
+		for _, node := range nodes {
+			if node.name != failoverState.Source {
+				failoverState.Target = node.name
+			}
+		}
+	default:
+		return failoverState, "target needed with more than one slave"
+	}
+	if failoverState.Target == failoverState.Source {
+		return failoverState, fmt.Sprintf("%v is already MASTER", failoverState.Target)
+	}
+
+	return failoverState, ""
+}
+
+// failover : on master API for move VIPs of a vrrp_sync_group (or one instance) to target node :
+// swap priorities of node MASTER and target, reload keepalived (target first) and wait state MASTER on target.
+// With restore, set priorities saved by last failover and wait state MASTER on node MASTER before failover.
+func failover(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	var failoverRequest failoverType
+	vars := mux.Vars(r)
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&failoverRequest)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	vrrpGroup := vars["vrrp_group"]
+	vrrpStates, err := readVrrpStatesMasterSlave()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	var instances []vrrpStateType
+	for _, vrrpState := range vrrpStates {
+		if vrrpState.VrrpGroup != vrrpGroup ||
+			(failoverRequest.Iface != "" && vrrpState.Iface != failoverRequest.Iface) ||
+			(failoverRequest.IDVrrp != "" && vrrpState.IDVrrp != failoverRequest.IDVrrp) {
+			continue
+		}
+		instances = append(instances, vrrpState)
+	}
+	if len(instances) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(w, "no vrrp instance in Vrrp_group", vrrpGroup)
+
+		return
+	}
+	nodes := nodesOps()
+	failoverState, failoverExists, err := readFailoverState(vrrpGroup)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	prios := make(map[string]map[string]string)
+	target := ""
+	if failoverRequest.Restore {
+		if !failoverExists {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, "no failover to restore on Vrrp_group", vrrpGroup)
+
+			return
+		}
+		prios = failoverState.Prios
+		target = failoverState.Source
+	} else {
+		if failoverExists {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, "failover already done on Vrrp_group", vrrpGroup, "(restore it before)")
+
+			return
+		}
+		var message string
+		failoverState, message = newFailoverState(instances, nodes, failoverRequest.Target)
+		if message != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, message)
+
+			return
+		}
+		for node, priosNode := range failoverState.Prios {
+			prios[node] = make(map[string]string)
+			for key, prio := range priosNode {
+				prios[node][key] = prio
+			}
+		}
+		for key := range prios[failoverState.Source] {
+			prios[failoverState.Source][key] = failoverState.Prios[failoverState.Target][key]
+			prios[failoverState.Target][key] = failoverState.Prios[failoverState.Source][key]
+		}
+		target = failoverState.Target
+	}
+	var targetNode nodeOpsType
+	nodesOrdered := make([]nodeOpsType, 0, len(nodes))
+	for _, node := range nodes {
+		if node.name == target {
+			targetNode = node
+			nodesOrdered = append([]nodeOpsType{node}, nodesOrdered...)
+		} else {
+			nodesOrdered = append(nodesOrdered, node)
+		}
+	}
+	if targetNode.name == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, "unknown node", target)
+
+		return
+	}
+	plan := planType{dryRun: r.URL.Query().Get("dry_run") == "true"}
+	// target first : with higher priority it becomes MASTER when priority of old MASTER is lowered
+	for _, node := range nodesOrdered {
+		var ifaceVrrps []ifaceVrrpType
+		for _, instance := range instances {
+			prio, ok := prios[node.name][vrrpStateKey(instance)]
+			if !ok || prio == instance.Nodes[node.name].Prio {
+				continue
+			}
+			ifaceVrrp, err := vrrpWithPrio(node, instance, prio)
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+
+				return
+			}
+			ifaceVrrps = append(ifaceVrrps, ifaceVrrp)
+		}
+		if len(ifaceVrrps) == 0 {
+			continue
+		}
+		err := plan.changeVrrpPriosSteps(node, ifaceVrrps)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+	}
+	plan.waitVrrpMasterStep(targetNode, instances)
+	if failoverRequest.Restore {
+		plan.add("remove saved priorities",
+			func() error {
+				return removeFailoverState(vrrpGroup)
+			},
+			func() error {
+				return saveFailoverState(vrrpGroup, failoverState)
+			})
+	} else {
+		plan.add("save priorities",
+			func() error {
+				return saveFailoverState(vrrpGroup, failoverState)
+			},
+			func() error {
+				return removeFailoverState(vrrpGroup)
+			})
+	}
+	runPlan(w, r, &plan)
+}
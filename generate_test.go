@@ -0,0 +1,463 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "write generated files in testdata/ instead of compare them")
+
+// generateContextType : flags used by generate functions.
+type generateContextType struct {
+	slave             bool
+	nodeName          string
+	keepalivedVersion string
+	notifyCommand     string
+}
+
+// use : point flags to values of context and return a function for set them back.
+func (context generateContextType) use() func() {
+	oldIsSlave, oldNodeName, oldVersion, oldNotify := isSlave, nodeName, keepalivedVersion, notifyCommand
+	isSlave, nodeName, notifyCommand = &context.slave, &context.nodeName, &context.notifyCommand
+	keepalivedVersion = "v2.0.20"
+	if context.keepalivedVersion != "" {
+		keepalivedVersion = context.keepalivedVersion
+	}
+
+	return func() {
+		isSlave, nodeName, keepalivedVersion, notifyCommand = oldIsSlave, oldNodeName, oldVersion, oldNotify
+	}
+}
+
+// checkGolden : compare content with testdata/name.golden (or write it with -update).
+func checkGolden(t *testing.T, name, content string) {
+	t.Helper()
+	golden := filepath.Join("testdata", name+".golden")
+	if *updateGolden {
+		err := os.MkdirAll(filepath.Dir(golden), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(golden, []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := unifiedDiff(golden, string(expected), content); diff != "" {
+		t.Errorf("generated file differs from %v :\n%v", golden, diff)
+	}
+}
+
+func testVips(count int) []string {
+	vips := make([]string, 0, count)
+	for i := 1; i <= count; i++ {
+		vips = append(vips, fmt.Sprintf("10.0.0.%d", 100+i))
+	}
+
+	return vips
+}
+
+func TestGenerateIfaceFile(t *testing.T) {
+	lacp := ifaceVrrpType{
+		Iface:            "bond1",
+		IPMaster:         "10.0.0.2",
+		IPSlave:          "10.0.0.3",
+		Mask:             "24",
+		LACPSlavesMaster: "eth1 eth2",
+		LACPSlavesSlave:  "eth3 eth4",
+	}
+	tests := []struct {
+		postupAdd bool
+		name      string
+		context   generateContextType
+		ifaceVrrp ifaceVrrpType
+	}{
+		{
+			postupAdd: true,
+			name:      "iface_ipv4_master",
+			ifaceVrrp: ifaceVrrpType{
+				Iface:     "eth1",
+				IPMaster:  "10.0.0.2",
+				IPSlave:   "10.0.0.3",
+				Mask:      "24",
+				DefaultGW: "10.0.0.254",
+				PostUp:    []string{"ip route add 10.1.0.0/16 via 10.0.0.253"},
+			},
+		},
+		{
+			postupAdd: false,
+			name:      "iface_ipv4_master_without_postup",
+			ifaceVrrp: ifaceVrrpType{
+				Iface:    "eth1",
+				IPMaster: "10.0.0.2",
+				IPSlave:  "10.0.0.3",
+				Mask:     "24",
+				PostUp:   []string{"ip route add 10.1.0.0/16 via 10.0.0.253"},
+			},
+		},
+		{
+			postupAdd: true,
+			name:      "iface_ipv4_slave",
+			context:   generateContextType{slave: true},
+			ifaceVrrp: ifaceVrrpType{
+				Iface:    "eth1",
+				IPMaster: "10.0.0.2",
+				IPSlave:  "10.0.0.3",
+				Mask:     "24",
+			},
+		},
+		{
+			postupAdd: true,
+			name:      "iface_ipv4_node",
+			context:   generateContextType{slave: true, nodeName: "slave2"},
+			ifaceVrrp: ifaceVrrpType{
+				Iface:    "eth1",
+				IPMaster: "10.0.0.2",
+				IPSlave:  "10.0.0.3",
+				Mask:     "24",
+				IPNodes:  map[string]string{"slave2": "10.0.0.4"},
+			},
+		},
+		{
+			postupAdd: true,
+			name:      "iface_ipv6",
+			ifaceVrrp: ifaceVrrpType{
+				Iface:     "eth1",
+				IPMaster:  "2001:db8::2",
+				IPSlave:   "2001:db8::3",
+				Mask:      "64",
+				DefaultGW: "2001:db8::1",
+			},
+		},
+		{
+			postupAdd: true,
+			name:      "iface_manual",
+			ifaceVrrp: ifaceVrrpType{
+				Iface: "eth1",
+			},
+		},
+		{
+			postupAdd: true,
+			name:      "iface_vlan",
+			ifaceVrrp: ifaceVrrpType{
+				Iface:      "vlan100",
+				IPMaster:   "10.0.0.2",
+				IPSlave:    "10.0.0.3",
+				Mask:       "24",
+				VlanDevice: "eth1",
+			},
+		},
+		{
+			postupAdd: true,
+			name:      "iface_lacp_master",
+			ifaceVrrp: lacp,
+		},
+		{
+			postupAdd: false,
+			name:      "iface_lacp_master_without_postup",
+			ifaceVrrp: lacp,
+		},
+		{
+			postupAdd: true,
+			name:      "iface_lacp_slave",
+			context:   generateContextType{slave: true},
+			ifaceVrrp: lacp,
+		},
+		{
+			postupAdd: false,
+			name:      "iface_lacp_slave_without_postup",
+			context:   generateContextType{slave: true},
+			ifaceVrrp: lacp,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			defer test.context.use()()
+			checkGolden(t, test.name, generateIfaceFile(test.ifaceVrrp, test.postupAdd))
+		})
+	}
+}
+
+func TestGenerateVrrpFile(t *testing.T) {
+	vrrp := func(update func(*ifaceVrrpType)) ifaceVrrpType {
+		ifaceVrrp := ifaceVrrpType{
+			Iface:      "eth1",
+			IPMaster:   "10.0.0.2",
+			IPSlave:    "10.0.0.3",
+			Mask:       "24",
+			VrrpGroup:  "VG_1",
+			IDVrrp:     "10",
+			PrioMaster: "150",
+			PrioSlave:  "100",
+			IPVip:      []string{"10.0.0.1"},
+		}
+		if update != nil {
+			update(&ifaceVrrp)
+		}
+
+		return ifaceVrrp
+	}
+	tests := []struct {
+		syncAdd   bool
+		name      string
+		context   generateContextType
+		ifaceVrrp ifaceVrrpType
+	}{
+		{
+			syncAdd:   true,
+			name:      "vrrp_ipv4_master",
+			ifaceVrrp: vrrp(nil),
+		},
+		{
+			syncAdd: true,
+			name:    "vrrp_ipv4_slave",
+			context: generateContextType{slave: true},
+			ifaceVrrp: vrrp(func(ifaceVrrp *ifaceVrrpType) {
+				ifaceVrrp.AuthType = "PASS"
+				ifaceVrrp.AuthPass = "secret"
+			}),
+		},
+		{
+			syncAdd: true,
+			name:    "vrrp_ipv4_node",
+			context: generateContextType{slave: true, nodeName: "slave2"},
+			ifaceVrrp: vrrp(func(ifaceVrrp *ifaceVrrpType) {
+				ifaceVrrp.PrioNodes = map[string]string{"slave2": "50"}
+			}),
+		},
+		{
+			syncAdd:   false,
+			name:      "vrrp_without_sync",
+			ifaceVrrp: vrrp(nil),
+		},
+		{
+			syncAdd: true,
+			name:    "vrrp_iface_for_vrrp",
+			ifaceVrrp: vrrp(func(ifaceVrrp *ifaceVrrpType) {
+				ifaceVrrp.IfaceForVrrp = "eth0"
+			}),
+		},
+		{
+			syncAdd: true,
+			name:    "vrrp_alias",
+			ifaceVrrp: vrrp(func(ifaceVrrp *ifaceVrrpType) {
+				ifaceVrrp.Iface = "eth1:1"
+			}),
+		},
+		{
+			syncAdd: true,
+			name:    "vrrp_options",
+			context: generateContextType{notifyCommand: "/usr/sbin/lvsnetwork-api -send_notify http://127.0.0.1:8080/notify/"},
+			ifaceVrrp: vrrp(func(ifaceVrrp *ifaceVrrpType) {
+				ifaceVrrp.TrackScript = []string{"chk_http", "chk_dns"}
+				ifaceVrrp.GarpMDelay = "10"
+				ifaceVrrp.GarpMasterRefresh = "60"
+				ifaceVrrp.AdvertInt = "3"
+				ifaceVrrp.AuthType = "AH"
+				ifaceVrrp.AuthPass = "secret"
+			}),
+		},
+		{
+			syncAdd: true,
+			name:    "vrrp_ipv6",
+			ifaceVrrp: vrrp(func(ifaceVrrp *ifaceVrrpType) {
+				ifaceVrrp.IPMaster = "2001:db8::2"
+				ifaceVrrp.IPSlave = "2001:db8::3"
+				ifaceVrrp.Mask = "64"
+				ifaceVrrp.IPVip = []string{"2001:db8::1"}
+				// without vmac and authentication in IPv6
+				ifaceVrrp.UseVmac = true
+				ifaceVrrp.AuthType = "PASS"
+				ifaceVrrp.AuthPass = "secret"
+			}),
+		},
+		{
+			syncAdd: true,
+			name:    "vrrp_vmac_short",
+			ifaceVrrp: vrrp(func(ifaceVrrp *ifaceVrrpType) {
+				ifaceVrrp.UseVmac = true
+			}),
+		},
+		{
+			syncAdd: true,
+			name:    "vrrp_vmac_long_iface",
+			ifaceVrrp: vrrp(func(ifaceVrrp *ifaceVrrpType) {
+				ifaceVrrp.Iface = "eth1.123"
+				ifaceVrrp.UseVmac = true
+			}),
+		},
+		{
+			syncAdd: true,
+			name:    "vrrp_vmac_long_id",
+			ifaceVrrp: vrrp(func(ifaceVrrp *ifaceVrrpType) {
+				ifaceVrrp.IDVrrp = "100"
+				ifaceVrrp.UseVmac = true
+			}),
+		},
+		{
+			syncAdd: true,
+			name:    "vrrp_excluded",
+			ifaceVrrp: vrrp(func(ifaceVrrp *ifaceVrrpType) {
+				ifaceVrrp.IPVip = testVips(22)
+			}),
+		},
+		{
+			syncAdd: true,
+			name:    "vrrp_vmac_short_excluded",
+			ifaceVrrp: vrrp(func(ifaceVrrp *ifaceVrrpType) {
+				ifaceVrrp.IPVip = testVips(22)
+				ifaceVrrp.UseVmac = true
+			}),
+		},
+		{
+			syncAdd: true,
+			name:    "vrrp_vmac_long_excluded",
+			ifaceVrrp: vrrp(func(ifaceVrrp *ifaceVrrpType) {
+				ifaceVrrp.Iface = "eth1.123"
+				ifaceVrrp.IPVip = testVips(22)
+				ifaceVrrp.UseVmac = true
+			}),
+		},
+		{
+			syncAdd:   true,
+			name:      "vrrp_track_interface",
+			context:   generateContextType{keepalivedVersion: "v1.3.5"},
+			ifaceVrrp: vrrp(nil),
+		},
+		{
+			syncAdd: true,
+			name:    "vrrp_sync_iface",
+			ifaceVrrp: vrrp(func(ifaceVrrp *ifaceVrrpType) {
+				ifaceVrrp.SyncIface = "eth0"
+			}),
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			defer test.context.use()()
+			vrrpFile, err := generateVrrpFile(test.ifaceVrrp, test.syncAdd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, test.name, vrrpFile)
+		})
+	}
+
+	t.Run("vrrp_vmac_too_long", func(t *testing.T) {
+		defer generateContextType{}.use()()
+		_, err := generateVrrpFile(vrrp(func(ifaceVrrp *ifaceVrrpType) {
+			ifaceVrrp.Iface = "bond0.1234"
+			ifaceVrrp.UseVmac = true
+		}), true)
+		if err == nil {
+			t.Error("no error with use_vmac on iface bond0.1234")
+		}
+	})
+}
+
+func testVrrpScripts() []vrrpScriptType {
+	return []vrrpScriptType{
+		{
+			Name:   "script_minimal",
+			Script: "/usr/local/bin/check",
+		},
+		{
+			InitFail: true,
+			Fall:     3,
+			Interval: 2,
+			Rise:     2,
+			Timeout:  5,
+			Weight:   -20,
+			Name:     "script_full",
+			Script:   "/usr/local/bin/check --port 80",
+			User:     "nobody",
+		},
+		{
+			WeightReverse: true,
+			Weight:        10,
+			Name:          "script_weight_reverse",
+			Script:        "/usr/local/bin/check",
+		},
+		{
+			WeightReverse: true,
+			Name:          "script_weight_zero_reverse",
+			Script:        "/usr/local/bin/check",
+		},
+	}
+}
+
+func TestGenerateScriptFile(t *testing.T) {
+	for _, vrrpScript := range testVrrpScripts() {
+		vrrpScript := vrrpScript
+		t.Run(vrrpScript.Name, func(t *testing.T) {
+			checkGolden(t, vrrpScript.Name, generateScriptFile(vrrpScript))
+		})
+	}
+}
+
+func TestReadVrrpScriptFile(t *testing.T) {
+	root, err := ioutil.TempDir("", "lvsnetwork-api-script")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	oldRootDir, oldKeepalivedDir := rootDir, keepalivedDir
+	defaultKeepalivedDir := "/etc/keepalived/keepalived-vrrp.d/"
+	rootDir, keepalivedDir = &root, &defaultKeepalivedDir
+	defer func() {
+		rootDir, keepalivedDir = oldRootDir, oldKeepalivedDir
+	}()
+	err = os.MkdirAll(keepalivedConfDir(), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, vrrpScript := range testVrrpScripts() {
+		vrrpScript := vrrpScript
+		t.Run(vrrpScript.Name, func(t *testing.T) {
+			err := ioutil.WriteFile(rootPath(vrrpScriptFilePath(vrrpScript.Name)),
+				[]byte(generateScriptFile(vrrpScript)), 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			vrrpScriptRead, err := readVrrpScriptFile(vrrpScript.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(vrrpScriptRead, vrrpScript) {
+				t.Errorf("vrrp_script read %+v instead of %+v", vrrpScriptRead, vrrpScript)
+			}
+		})
+	}
+
+	for name, content := range map[string]string{
+		"bad_name":      "vrrp_script other {\n\tscript \"/bin/true\"\n}\n",
+		"unknown_line":  "vrrp_script bad_name {\n\tscript \"/bin/true\"\n\tunknown 1\n}\n",
+		"bad_integer":   "vrrp_script bad_name {\n\tscript \"/bin/true\"\n\tfall x\n}\n",
+		"without_close": "vrrp_script bad_name {\n\tscript \"/bin/true\"\n",
+	} {
+		content := content
+		t.Run(name, func(t *testing.T) {
+			err := ioutil.WriteFile(rootPath(vrrpScriptFilePath("bad_name")), []byte(content), 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = readVrrpScriptFile("bad_name")
+			if err == nil {
+				t.Errorf("no error on read of :\n%v", content)
+			}
+		})
+	}
+}
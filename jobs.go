@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	auth "github.com/abbot/go-http-auth"
+	"github.com/gorilla/mux"
+)
+
+const (
+	jobPending = "pending"
+	jobRunning = "running"
+	jobDone    = "done"
+	jobFailed  = "failed"
+)
+
+type jobType struct {
+	StatusCode int      `json:"status_code"`
+	ID         string   `json:"id"`
+	Method     string   `json:"method"`
+	URL        string   `json:"url"`
+	Status     string   `json:"status"`
+	Result     string   `json:"result"`
+	Created    string   `json:"created"`
+	Updated    string   `json:"updated"`
+	Steps      []string `json:"steps"`
+}
+
+type jobContextKey struct{}
+
+var (
+	jobs      = make(map[string]*jobType)
+	jobsMutex = &sync.Mutex{}
+)
+
+// jobResponseWriter : http.ResponseWriter for keep status code and body of a request run in a job.
+type jobResponseWriter struct {
+	statusCode int
+	header     http.Header
+	body       bytes.Buffer
+}
+
+func (jw *jobResponseWriter) Header() http.Header {
+	return jw.header
+}
+
+func (jw *jobResponseWriter) Write(b []byte) (int, error) {
+	if jw.statusCode == 0 {
+		jw.statusCode = http.StatusOK
+	}
+
+	return jw.body.Write(b)
+}
+
+func (jw *jobResponseWriter) WriteHeader(statusCode int) {
+	if jw.statusCode == 0 {
+		jw.statusCode = statusCode
+	}
+}
+
+// jobsDir : directory for persist jobs.
+func jobsDir() string {
+	return filepath.Join(*stateDir, "jobs")
+}
+
+// loadJobs : read jobs persisted in state directory, jobs not finished are failed (interrupted by restart).
+func loadJobs() error {
+	err := os.MkdirAll(jobsDir(), os.FileMode(permissionFileCreated))
+	if err != nil {
+		return err
+	}
+	files, err := filepath.Glob(filepath.Join(jobsDir(), "*.json"))
+	if err != nil {
+		return err
+	}
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+	for _, file := range files {
+		jobByte, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var job jobType
+		err = json.Unmarshal(jobByte, &job)
+		if err != nil {
+			log.Printf("job file %v ignored : %v", file, err)
+
+			continue
+		}
+		if job.Status == jobPending || job.Status == jobRunning {
+			job.Status = jobFailed
+			job.Result = "interrupted by restart of lvsnetwork-api"
+			job.Updated = time.Now().UTC().Format(time.RFC3339)
+			err := saveJob(&job)
+			if err != nil {
+				return err
+			}
+		}
+		jobs[job.ID] = &job
+	}
+	pruneJobs()
+
+	return nil
+}
+
+// pruneJobs : remove finished jobs updated before -jobs_ttl (jobsMutex must be locked).
+func pruneJobs() {
+	if *jobsTTL <= 0 {
+		return
+	}
+	expire := time.Now().UTC().Add(-time.Duration(*jobsTTL) * time.Hour)
+	for id, job := range jobs {
+		if job.Status != jobDone && job.Status != jobFailed {
+			continue
+		}
+		updated, err := time.Parse(time.RFC3339, job.Updated)
+		if err == nil && updated.After(expire) {
+			continue
+		}
+		err = os.Remove(filepath.Join(jobsDir(), strings.Join([]string{id, ".json"}, "")))
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("remove job %v error : %v", id, err)
+
+			continue
+		}
+		delete(jobs, id)
+	}
+}
+
+// saveJob : write job in state directory (jobsMutex must be locked).
+func saveJob(job *jobType) error {
+	jobByte, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(jobsDir(), strings.Join([]string{job.ID, ".json"}, "")), jobByte, 0o644)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updateJob : modify job with function and persist it.
+func updateJob(job *jobType, update func(*jobType)) {
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+	update(job)
+	job.Updated = time.Now().UTC().Format(time.RFC3339)
+	err := saveJob(job)
+	if err != nil {
+		log.Printf("save job %v error : %v", job.ID, err)
+	}
+}
+
+// jobFromContext : job of request if it runs in a job, nil otherwise.
+func jobFromContext(ctx context.Context) *jobType {
+	job, ok := ctx.Value(jobContextKey{}).(*jobType)
+	if !ok {
+		return nil
+	}
+
+	return job
+}
+
+// jobStepDone : add step done in progress of job.
+func jobStepDone(job *jobType) func(string) {
+	return func(step string) {
+		updateJob(job, func(job *jobType) {
+			job.Steps = append(job.Steps, step)
+		})
+	}
+}
+
+// newJobID : random id for job.
+func newJobID() (string, error) {
+	id := make([]byte, 8) // nolint: gomnd
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
+// asyncMiddleware : with ?async=true on request which changes configuration,
+// return 202 with id of job and run request in background.
+func asyncMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("async") != "true" || !mutatingRequest(r) {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+		if configHtpasswd() != "" {
+			htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+			authenticator := auth.BasicAuth{
+				Realm:   "Basic Realm",
+				Secrets: htpasswd,
+			}
+			usercheck := authenticator.CheckAuth(r)
+			if usercheck == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+
+				return
+			}
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+		id, err := newJobID()
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+		job := &jobType{
+			ID:      id,
+			Method:  r.Method,
+			URL:     r.URL.String(),
+			Status:  jobPending,
+			Created: time.Now().UTC().Format(time.RFC3339),
+			Steps:   make([]string, 0),
+		}
+		updateJob(job, func(job *jobType) {
+			pruneJobs()
+			jobs[job.ID] = job
+		})
+		// request without cancel of client connection but with vars of route
+		jobRequest := r.Clone(context.WithValue(context.Background(), jobContextKey{}, job))
+		jobRequest = mux.SetURLVars(jobRequest, mux.Vars(r))
+		jobRequest.Body = ioutil.NopCloser(bytes.NewReader(body))
+		js, err := json.Marshal(job)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+		go func() {
+			updateJob(job, func(job *jobType) {
+				job.Status = jobRunning
+			})
+			jw := &jobResponseWriter{header: make(http.Header)}
+			next.ServeHTTP(jw, jobRequest)
+			if jw.statusCode == 0 {
+				jw.statusCode = http.StatusOK
+			}
+			updateJob(job, func(job *jobType) {
+				job.StatusCode = jw.statusCode
+				job.Result = jw.body.String()
+				if jw.statusCode >= http.StatusBadRequest {
+					job.Status = jobFailed
+				} else {
+					job.Status = jobDone
+				}
+			})
+		}()
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", strings.Join([]string{"/jobs/", job.ID, "/"}, ""))
+		w.WriteHeader(http.StatusAccepted)
+		_, err = w.Write(js)
+		if err != nil {
+			log.Printf("write response of job %v error : %v", job.ID, err)
+		}
+	})
+}
+
+// listJobs : on master API for list jobs sorted by creation, without body.
+func listJobs(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	jobsMutex.Lock()
+	jobsList := make([]jobType, 0, len(jobs))
+	for _, job := range jobs {
+		jobsList = append(jobsList, *job)
+	}
+	jobsMutex.Unlock()
+	sort.Slice(jobsList, func(i, j int) bool {
+		if jobsList[i].Created == jobsList[j].Created {
+			return jobsList[i].ID < jobsList[j].ID
+		}
+
+		return jobsList[i].Created < jobsList[j].Created
+	})
+	js, err := json.Marshal(jobsList)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// getJob : on master API for read status, steps done and result of a job, without body.
+func getJob(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	vars := mux.Vars(r)
+	jobsMutex.Lock()
+	job, ok := jobs[vars["id"]]
+	var jobCopy jobType
+	if ok {
+		jobCopy = *job
+	}
+	jobsMutex.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(w, "unknown job", vars["id"])
+
+		return
+	}
+	js, err := json.Marshal(jobCopy)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
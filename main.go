@@ -3,14 +3,12 @@ package main
 import (
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"os"
-	"os/exec"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
@@ -18,30 +16,32 @@ import (
 )
 
 type ifaceVrrpType struct {
-	IPVipOnly         bool     `json:"IP_vip_only"`
-	UseVmac           bool     `json:"Use_vmac"`
-	Iface             string   `json:"iface"`
-	IPMaster          string   `json:"IP_master"`
-	IPSlave           string   `json:"IP_slave"`
-	Mask              string   `json:"Mask"`
-	PrioMaster        string   `json:"Prio_master"`
-	PrioSlave         string   `json:"Prio_slave"`
-	VlanDevice        string   `json:"Vlan_device"`
-	VrrpGroup         string   `json:"Vrrp_group"`
-	IfaceForVrrp      string   `json:"Iface_vrrp"`
-	IDVrrp            string   `json:"Id_vrrp"`
-	AuthType          string   `json:"Auth_type"`
-	AuthPass          string   `json:"Auth_pass"`
-	DefaultGW         string   `json:"Default_GW"`
-	LACPSlavesMaster  string   `json:"LACP_slaves_master"`
-	LACPSlavesSlave   string   `json:"LACP_slaves_slave"`
-	SyncIface         string   `json:"Sync_iface"`
-	GarpMDelay        string   `json:"Garp_m_delay"`
-	GarpMasterRefresh string   `json:"Garp_master_refresh"`
-	AdvertInt         string   `json:"Advert_int"`
-	IPVip             []string `json:"IP_vip"`
-	PostUp            []string `json:"Post_up"`
-	TrackScript       []string `json:"track_script"`
+	IPVipOnly         bool              `json:"IP_vip_only"`
+	UseVmac           bool              `json:"Use_vmac"`
+	Iface             string            `json:"iface"`
+	IPMaster          string            `json:"IP_master"`
+	IPSlave           string            `json:"IP_slave"`
+	Mask              string            `json:"Mask"`
+	PrioMaster        string            `json:"Prio_master"`
+	PrioSlave         string            `json:"Prio_slave"`
+	VlanDevice        string            `json:"Vlan_device"`
+	VrrpGroup         string            `json:"Vrrp_group"`
+	IfaceForVrrp      string            `json:"Iface_vrrp"`
+	IDVrrp            string            `json:"Id_vrrp"`
+	AuthType          string            `json:"Auth_type"`
+	AuthPass          string            `json:"Auth_pass"`
+	DefaultGW         string            `json:"Default_GW"`
+	LACPSlavesMaster  string            `json:"LACP_slaves_master"`
+	LACPSlavesSlave   string            `json:"LACP_slaves_slave"`
+	SyncIface         string            `json:"Sync_iface"`
+	GarpMDelay        string            `json:"Garp_m_delay"`
+	GarpMasterRefresh string            `json:"Garp_master_refresh"`
+	AdvertInt         string            `json:"Advert_int"`
+	IPVip             []string          `json:"IP_vip"`
+	PostUp            []string          `json:"Post_up"`
+	TrackScript       []string          `json:"track_script"`
+	IPNodes           map[string]string `json:"IP_nodes"`
+	PrioNodes         map[string]string `json:"Prio_nodes"`
 }
 
 type vrrpScriptType struct {
@@ -57,15 +57,202 @@ type vrrpScriptType struct {
 	User          string `json:"user"`
 }
 
+type virtualServerType struct {
+	Port               int              `json:"port"`
+	PersistenceTimeout int              `json:"persistence_timeout"`
+	Name               string           `json:"name"`
+	VIP                string           `json:"vip"`
+	Protocol           string           `json:"protocol"`
+	LbAlgo             string           `json:"lb_algo"`
+	LbKind             string           `json:"lb_kind"`
+	RealServers        []realServerType `json:"real_server"`
+}
+
+type realServerType struct {
+	Port    int         `json:"port"`
+	Weight  int         `json:"weight"`
+	IP      string      `json:"ip"`
+	Checker checkerType `json:"checker"`
+}
+
+type checkerType struct {
+	ConnectPort      int    `json:"connect_port"`
+	ConnectTimeout   int    `json:"connect_timeout"`
+	Retry            int    `json:"retry"`
+	DelayBeforeRetry int    `json:"delay_before_retry"`
+	StatusCode       int    `json:"status_code"`
+	MiscTimeout      int    `json:"misc_timeout"`
+	Type             string `json:"type"`
+	Path             string `json:"path"`
+	Digest           string `json:"digest"`
+	MiscPath         string `json:"misc_path"`
+}
+
+type inventoryType struct {
+	Ifaces         []inventoryItemType `json:"ifaces"`
+	Vrrps          []inventoryItemType `json:"vrrps"`
+	VrrpScripts    []inventoryItemType `json:"vrrp_scripts"`
+	VirtualServers []inventoryItemType `json:"virtual_servers"`
+}
+
+type inventoryItemType struct {
+	Master     bool   `json:"master"`
+	Slave      bool   `json:"slave"`
+	Consistent bool   `json:"consistent"`
+	Name       string `json:"name"`
+	VrrpGroup  string `json:"Vrrp_group,omitempty"`
+	IDVrrp     string `json:"Id_vrrp,omitempty"`
+	Hash       string `json:"hash"`
+}
+
+type diffType struct {
+	Iface         string `json:"iface"`
+	Vrrp          string `json:"vrrp"`
+	VrrpScript    string `json:"vrrp_script"`
+	VirtualServer string `json:"virtual_server"`
+}
+
+type diffReportType struct {
+	Master diffType            `json:"master"`
+	Slave  diffType            `json:"slave"`
+	Peers  map[string]diffType `json:"peers,omitempty"`
+}
+
+type managedFileType struct {
+	Exists  bool   `json:"exists"`
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+type snapshotType struct {
+	Version int                          `json:"version"`
+	Date    string                       `json:"date"`
+	Nodes   map[string][]managedFileType `json:"nodes"`
+}
+
+type dryRunType struct {
+	Steps []string                  `json:"steps"`
+	Nodes map[string]dryRunNodeType `json:"nodes"`
+}
+
+type dryRunNodeType struct {
+	Write    []managedFileType `json:"write"`
+	Remove   []string          `json:"remove"`
+	Commands []string          `json:"commands"`
+}
+
+type vrrpStateType struct {
+	Iface     string                       `json:"iface"`
+	VrrpGroup string                       `json:"Vrrp_group"`
+	IDVrrp    string                       `json:"Id_vrrp"`
+	Instance  string                       `json:"instance"`
+	State     string                       `json:"state,omitempty"`
+	Prio      string                       `json:"prio,omitempty"`
+	Error     string                       `json:"error,omitempty"`
+	Nodes     map[string]vrrpNodeStateType `json:"nodes,omitempty"`
+}
+
+type vrrpNodeStateType struct {
+	Maintenance bool   `json:"maintenance,omitempty"`
+	State       string `json:"state"`
+	Prio        string `json:"prio"`
+	Error       string `json:"error,omitempty"`
+}
+
+type failoverType struct {
+	Restore bool   `json:"restore"`
+	Iface   string `json:"iface"`
+	IDVrrp  string `json:"Id_vrrp"`
+	Target  string `json:"target"`
+}
+
+type failoverStateType struct {
+	Source string                       `json:"source"`
+	Target string                       `json:"target"`
+	Prios  map[string]map[string]string `json:"prios"`
+}
+
+type maintenanceType struct {
+	Node  string            `json:"node"`
+	Date  string            `json:"date"`
+	Prios map[string]string `json:"prios"`
+	Error string            `json:"error,omitempty"`
+}
+
+type notifyType struct {
+	Node  string `json:"node"`
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	State string `json:"state"`
+	Prio  string `json:"prio,omitempty"`
+	Date  string `json:"date"`
+}
+
+type auditType struct {
+	StatusCode int             `json:"status_code"`
+	Date       string          `json:"date"`
+	User       string          `json:"user"`
+	Method     string          `json:"method"`
+	Endpoint   string          `json:"endpoint"`
+	Result     string          `json:"result,omitempty"`
+	FilesError string          `json:"files_error,omitempty"`
+	Request    interface{}     `json:"request,omitempty"`
+	Files      []auditFileType `json:"files,omitempty"`
+}
+
+type auditFileType struct {
+	Created bool   `json:"created,omitempty"`
+	Removed bool   `json:"removed,omitempty"`
+	Node    string `json:"node"`
+	Path    string `json:"path"`
+	Before  string `json:"before,omitempty"`
+	After   string `json:"after,omitempty"`
+}
+
+type v2ErrorType struct {
+	Code    string                `json:"code"`
+	Message string                `json:"message"`
+	Errors  []validationErrorType `json:"errors,omitempty"`
+}
+
+type validationErrorType struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type validationErrorsType struct {
+	Errors []validationErrorType `json:"errors"`
+}
+
+type peerType struct {
+	Name string
+	IP   string
+	Port string
+}
+
 var (
 	htpasswdfile            *string
 	isSlave                 *bool
 	listenIPSlave           *string
 	listenPortSlave         *string
 	httpsSlave              *bool
-	timeSleep               *int
+	readyTimeout            *int
+	keepalivedPidFile       *string
+	keepalivedDataFile      *string
 	reloadKeepalivedCommand *string
 	debug                   *bool
+	nodeName                *string
+	stateDir                *string
+	jobsTTL                 *int
+	notifyCommand           *string
+	webhooks                *string
+	auditLogFile            *string
+	configFile              *string
+	ifaceDir                *string
+	keepalivedDir           *string
+	rootDir                 *string
+	peers                   []peerType
 	mutex                   = &sync.Mutex{}
 	keepalivedVersion       string
 )
@@ -76,24 +263,75 @@ const (
 )
 
 func main() {
+	configFile = flag.String("config", "",
+		"YAML config file with flags as keys (flags on command line override it, SIGHUP reloads a part)")
 	listenIP := flag.String("ip", "127.0.0.1", "listen on IP")
 	listenPort := flag.String("port", "8080", "listen on port")
 	https := flag.Bool("https", false, "https = true or false")
 	cert := flag.String("cert", "", "file of certificat for https")
 	key := flag.String("key", "", "file of key for https")
 	accessLogFile := flag.String("log", "/var/log/lvsnetwork-api.access.log", "file for access log")
+	auditLogFile = flag.String("audit_log", "/var/log/lvsnetwork-api.audit.log",
+		"file for audit log of configuration changes on master (empty for disable)")
 	htpasswdfile = flag.String("htpasswd", "", "htpasswd file for login:password")
 	isSlave = flag.Bool("is_slave", false, "slave ?")
 	listenIPSlave = flag.String("ip_slave", "172.17.197.82", "listen slave on IP")
 	listenPortSlave = flag.String("port_slave", "8080", "listen slave on port")
 	httpsSlave = flag.Bool("https_slave", false, "https for request from master to slave ?")
-	timeSleep = flag.Int("sleep", 10, "time for sleep before check iface communicate")
+	flag.Int("sleep", 0, "deprecated, ignored (replaced by ready_timeout)")
+	readyTimeout = flag.Int("ready_timeout", 60,
+		"timeout in seconds for wait iface up, communication between nodes and vrrp instance ready")
+	keepalivedPidFile = flag.String("keepalived_pid", "/var/run/keepalived.pid",
+		"pid file of keepalived process (SIGUSR1 for read vrrp states)")
+	keepalivedDataFile = flag.String("keepalived_data", "/tmp/keepalived.data",
+		"file written by keepalived on SIGUSR1")
 	reloadKeepalivedCommand = flag.String("reload_cmd", "/etc/init.d/keepalived-vrrp reload",
 		"command for reload vrrp keepalived process")
 	debug = flag.Bool("debug", false, "debug for file comparison")
+	nodeName = flag.String("node_name", "", "name of this node for IP_nodes and Prio_nodes")
+	stateDir = flag.String("state_dir", "/var/lib/lvsnetwork-api/", "directory for state of master (jobs)")
+	jobsTTL = flag.Int("jobs_ttl", 168, "hours before remove of finished jobs (0 for keep them)")
+	ifaceDir = flag.String("iface_dir", "/etc/network/interfaces.d/", "directory for iface configuration")
+	keepalivedDir = flag.String("keepalived_dir", "/etc/keepalived/keepalived-vrrp.d/",
+		"directory for vrrp configuration (included by keepalived)")
+	rootDir = flag.String("root", "", "prefix for iface_dir and keepalived_dir (run on an alternate tree)")
+	peersList := flag.String("peers", "",
+		"list of slave peers name=ip:port separated by comma (default: slave=ip_slave:port_slave)")
+	notifyCommand = flag.String("notify_cmd", "",
+		"notify command added in vrrp instances and sync groups "+
+			"(ex: \"/usr/sbin/lvsnetwork-api -send_notify http://127.0.0.1:8080/notify/\")")
+	webhooks = flag.String("webhooks", "", "list of URL separated by comma for POST of vrrp transitions")
+	sendNotify := flag.String("send_notify", "",
+		"send vrrp transition (arguments of keepalived notify) to URL of /notify/ and exit")
 
 	flag.Parse()
 
+	if *sendNotify != "" {
+		err := sendNotification(*sendNotify, flag.Args())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	err := loadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !strings.HasSuffix(*ifaceDir, "/") {
+		*ifaceDir = strings.Join([]string{*ifaceDir, "/"}, "")
+	}
+	if !strings.HasSuffix(*keepalivedDir, "/") {
+		*keepalivedDir = strings.Join([]string{*keepalivedDir, "/"}, "")
+	}
+	reloadConfigOnSighup()
+
+	peers, err = parsePeers(*peersList)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// accesslog file open
 	accessLog, err := os.OpenFile(*accessLogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
 	if err != nil {
@@ -103,31 +341,8 @@ func main() {
 	checkIfupdownVersion()
 	checkKeepalivedVersion()
 
-	// create router
-	router := mux.NewRouter().StrictSlash(true)
 	if *isSlave {
-		router.HandleFunc("/check_iface_exists/{iface}/", onslaveCheckIfaceExists)
-		router.HandleFunc("/check_iface_ok/{iface}/", onslaveCheckIfaceOk)
-		router.HandleFunc("/check_iface_without_postup/{iface}/", onslaveCheckIfaceWithoutPostup)
-		router.HandleFunc("/add_iface/{iface}/", onslaveAddIface)
-		router.HandleFunc("/add_iface_file/{iface}/", onslaveAddIfaceFile)
-		router.HandleFunc("/remove_iface/{iface}/", onslaveRemoveIface)
-		router.HandleFunc("/remove_iface_file/{iface}/", onslaveRemoveIfaceFile)
-		router.HandleFunc("/change_iface_postup/{iface}/", onslaveChangeIfacePostup)
-		router.HandleFunc("/check_vrrp_exists/{iface}/", onslaveCheckVrrpExists)
-		router.HandleFunc("/check_vrrp_exists_otherVG/{iface}/", onslaveCheckVrrpExistsOtherVG)
-		router.HandleFunc("/check_vrrp_ok/{iface}/", onslaveCheckVrrpOk)
-		router.HandleFunc("/check_vrrp_without_sync/{iface}/", onslaveCheckVrrpWithoutSync)
-		router.HandleFunc("/add_vrrp/{iface}/", onslaveAddVrrp)
-		router.HandleFunc("/remove_vrrp/{iface}/", onslaveRemoveVrrp)
-		router.HandleFunc("/reload_vrrp/", onslaveReloadVrrp)
-		router.HandleFunc("/sync_group_reload_vrrp/", onslaveSyncGroupAndReload)
-		router.HandleFunc("/check_vrrp_script_exists/{name}/", onslaveCheckVrrpScriptExists)
-		router.HandleFunc("/check_vrrp_script_ok/{name}/", onslaveCheckVrrpScriptOk)
-		router.HandleFunc("/add_vrrp_script/{name}/", onslaveAddVrrpScript)
-		router.HandleFunc("/remove_vrrp_script/{name}/", onslaveRemoveVrrpScript)
-
-		loggedRouter := handlers.CombinedLoggingHandler(accessLog, router)
+		loggedRouter := handlers.CombinedLoggingHandler(accessLog, slaveRouter())
 
 		if *https {
 			if (*cert == "") || (*key == "") {
@@ -141,17 +356,12 @@ func main() {
 				[]string{*listenIPSlave, ":", *listenPortSlave}, ""), loggedRouter))
 		}
 	} else {
-		router.HandleFunc("/add_iface_vrrp/{iface}/", addIfaceVrrp)
-		router.HandleFunc("/remove_iface_vrrp/{iface}/", removeIfaceVrrp)
-		router.HandleFunc("/check_iface_vrrp/{iface}/", checkIfaceVrrp)
-		router.HandleFunc("/change_iface_vrrp/{iface}/", changeIfaceVrrp)
-		router.HandleFunc("/moveid_iface_vrrp/{iface}/{old_Id_vrrp}/", moveIDIfaceVrrp)
-		router.HandleFunc("/add_vrrp_script/{name}/", addVrrpScript)
-		router.HandleFunc("/remove_vrrp_script/{name}/", removeVrrpScript)
-		router.HandleFunc("/check_vrrp_script/{name}/", checkVrrpScript)
-		router.HandleFunc("/change_vrrp_script/{name}/", changeVrrpScript)
-
-		loggedRouter := handlers.CombinedLoggingHandler(accessLog, router)
+		err := loadJobs()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		loggedRouter := handlers.CombinedLoggingHandler(accessLog, masterRouter())
 
 		if *https {
 			if (*cert == "") || (*key == "") {
@@ -166,19 +376,109 @@ func main() {
 	}
 }
 
+// slaveRouter : routes of API on slave.
+func slaveRouter() *mux.Router {
+	router := mux.NewRouter().StrictSlash(true)
+	router.HandleFunc("/check_iface_exists/{iface}/", onslaveCheckIfaceExists)
+	router.HandleFunc("/check_iface_ok/{iface}/", onslaveCheckIfaceOk)
+	router.HandleFunc("/check_iface_without_postup/{iface}/", onslaveCheckIfaceWithoutPostup)
+	router.HandleFunc("/add_iface/{iface}/", onslaveAddIface)
+	router.HandleFunc("/add_iface_file/{iface}/", onslaveAddIfaceFile)
+	router.HandleFunc("/remove_iface/{iface}/", onslaveRemoveIface)
+	router.HandleFunc("/remove_iface_file/{iface}/", onslaveRemoveIfaceFile)
+	router.HandleFunc("/change_iface_postup/{iface}/", onslaveChangeIfacePostup)
+	router.HandleFunc("/check_vrrp_exists/{iface}/", onslaveCheckVrrpExists)
+	router.HandleFunc("/check_vrrp_exists_otherVG/{iface}/", onslaveCheckVrrpExistsOtherVG)
+	router.HandleFunc("/check_vrrp_ok/{iface}/", onslaveCheckVrrpOk)
+	router.HandleFunc("/check_vrrp_without_sync/{iface}/", onslaveCheckVrrpWithoutSync)
+	router.HandleFunc("/add_vrrp/{iface}/", onslaveAddVrrp)
+	router.HandleFunc("/remove_vrrp/{iface}/", onslaveRemoveVrrp)
+	router.HandleFunc("/reload_vrrp/", onslaveReloadVrrp)
+	router.HandleFunc("/sync_group_reload_vrrp/", onslaveSyncGroupAndReload)
+	router.HandleFunc("/wait_keepalived/", onslaveWaitKeepalived)
+	router.HandleFunc("/wait_vrrp/{iface}/", onslaveWaitVrrp)
+	router.HandleFunc("/wait_vrrp_removed/{iface}/", onslaveWaitVrrpRemoved)
+	router.HandleFunc("/state_vrrp/", onslaveStateVrrp)
+	router.HandleFunc("/notify/", notify)
+	router.HandleFunc("/notify_history/", onslaveNotifyHistory)
+	router.HandleFunc("/metrics", onslaveMetrics)
+	router.HandleFunc("/check_vrrp_script_exists/{name}/", onslaveCheckVrrpScriptExists)
+	router.HandleFunc("/check_vrrp_script_ok/{name}/", onslaveCheckVrrpScriptOk)
+	router.HandleFunc("/add_vrrp_script/{name}/", onslaveAddVrrpScript)
+	router.HandleFunc("/remove_vrrp_script/{name}/", onslaveRemoveVrrpScript)
+	router.HandleFunc("/check_virtual_server_exists/{name}/", onslaveCheckVirtualServerExists)
+	router.HandleFunc("/check_virtual_server_ok/{name}/", onslaveCheckVirtualServerOk)
+	router.HandleFunc("/add_virtual_server/{name}/", onslaveAddVirtualServer)
+	router.HandleFunc("/remove_virtual_server/{name}/", onslaveRemoveVirtualServer)
+	router.HandleFunc("/list/", onslaveList)
+	router.HandleFunc("/read_iface_vrrp/{iface}/", onslaveReadIfaceVrrp)
+	router.HandleFunc("/diff_iface_vrrp/{iface}/", onslaveDiffIfaceVrrp)
+	router.HandleFunc("/diff_vrrp_script/{name}/", onslaveDiffVrrpScript)
+	router.HandleFunc("/diff_virtual_server/{name}/", onslaveDiffVirtualServer)
+	router.HandleFunc("/get_file/", onslaveGetFile)
+	router.HandleFunc("/put_file/", onslavePutFile)
+	router.HandleFunc("/get_files/", onslaveGetFiles)
+	router.HandleFunc("/ifup/{iface}/", onslaveIfupIface)
+	router.HandleFunc("/ifdown/{iface}/", onslaveIfdownIface)
+	router.HandleFunc("/generate_iface_vrrp/{iface}/", onslaveGenerateIfaceVrrp)
+
+	router.Use(metricsMiddleware)
+
+	return router
+}
+
+// masterRouter : routes of API on master.
+func masterRouter() *mux.Router {
+	router := mux.NewRouter().StrictSlash(true)
+	router.HandleFunc("/add_iface_vrrp/{iface}/", addIfaceVrrp)
+	router.HandleFunc("/remove_iface_vrrp/{iface}/", removeIfaceVrrp)
+	router.HandleFunc("/check_iface_vrrp/{iface}/", checkIfaceVrrp)
+	router.HandleFunc("/change_iface_vrrp/{iface}/", changeIfaceVrrp)
+	router.HandleFunc("/moveid_iface_vrrp/{iface}/{old_Id_vrrp}/", moveIDIfaceVrrp)
+	router.HandleFunc("/add_vrrp_script/{name}/", addVrrpScript)
+	router.HandleFunc("/remove_vrrp_script/{name}/", removeVrrpScript)
+	router.HandleFunc("/check_vrrp_script/{name}/", checkVrrpScript)
+	router.HandleFunc("/change_vrrp_script/{name}/", changeVrrpScript)
+	router.HandleFunc("/add_virtual_server/{name}/", addVirtualServer)
+	router.HandleFunc("/remove_virtual_server/{name}/", removeVirtualServer)
+	router.HandleFunc("/check_virtual_server/{name}/", checkVirtualServer)
+	router.HandleFunc("/change_virtual_server/{name}/", changeVirtualServer)
+	router.HandleFunc("/add_real_server/{name}/", addRealServer)
+	router.HandleFunc("/remove_real_server/{name}/", removeRealServer)
+	router.HandleFunc("/drain_real_server/{name}/", drainRealServer)
+	router.HandleFunc("/list_iface_vrrp/", listIfaceVrrp)
+	router.HandleFunc("/import_iface_vrrp/{iface}/", importIfaceVrrp)
+	router.HandleFunc("/diff_iface_vrrp/{iface}/", diffIfaceVrrp)
+	router.HandleFunc("/diff_vrrp_script/{name}/", diffVrrpScript)
+	router.HandleFunc("/diff_virtual_server/{name}/", diffVirtualServer)
+	router.HandleFunc("/list_vrrp_script/", listVrrpScript)
+	router.HandleFunc("/list_virtual_server/", listVirtualServer)
+	router.HandleFunc("/state_vrrp/", stateVrrp)
+	router.HandleFunc("/notify/", notify)
+	router.HandleFunc("/notify_history/", notifyHistory)
+	router.HandleFunc("/metrics", getMetrics)
+	router.HandleFunc("/audit/", listAudit)
+	router.HandleFunc("/failover/{vrrp_group}/", failover)
+	router.HandleFunc("/maintenance/", listMaintenance)
+	router.HandleFunc("/enter_maintenance/{node}/", enterMaintenance)
+	router.HandleFunc("/exit_maintenance/{node}/", exitMaintenance)
+	router.HandleFunc("/snapshot/", snapshot)
+	router.HandleFunc("/restore/", restore)
+	router.HandleFunc("/jobs/", listJobs)
+	router.HandleFunc("/jobs/{id}/", getJob)
+	v2Routes(router)
+	router.HandleFunc("/openapi.json", getOpenAPI(router))
+	router.Use(metricsMiddleware)
+	router.Use(maintenanceMiddleware)
+	router.Use(asyncMiddleware)
+	router.Use(auditMiddleware)
+
+	return router
+}
+
 // checkIfupdownVersion : test ifquery version for --state options.
 func checkIfupdownVersion() {
-	cmd := exec.Command("ifquery", "--help")
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		log.Fatal(err)
-	}
-	if err := cmd.Start(); err != nil {
-		log.Fatal(err)
-	}
-	returnCmd, _ := ioutil.ReadAll(stdout)
-
-	err = cmd.Wait()
+	returnCmd, err := executor.run("ifquery", "--help")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -188,17 +488,7 @@ func checkIfupdownVersion() {
 }
 
 func checkKeepalivedVersion() {
-	cmd := exec.Command("keepalived", "-v")
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		log.Fatal(err)
-	}
-	if err := cmd.Start(); err != nil {
-		log.Fatal(err)
-	}
-	returnCmd, _ := ioutil.ReadAll(stderr)
-
-	err = cmd.Wait()
+	returnCmd, err := executor.run("keepalived", "-v")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -213,7 +503,31 @@ func checkKeepalivedVersion() {
 	}
 }
 
-// sleep : just wait.
-func sleep() {
-	time.Sleep(time.Duration(*timeSleep) * time.Second)
+// parsePeers : read list of slave peers, -ip_slave and -port_slave for only one peer if list is empty.
+func parsePeers(peersList string) ([]peerType, error) {
+	if peersList == "" {
+		return []peerType{{
+			Name: "slave",
+			IP:   *listenIPSlave,
+			Port: *listenPortSlave,
+		}}, nil
+	}
+	peersParsed := make([]peerType, 0)
+	for _, peer := range strings.Split(peersList, ",") {
+		peerSplit := strings.SplitN(peer, "=", 2) // nolint: gomnd
+		if len(peerSplit) != 2 {                  // nolint: gomnd
+			return peersParsed, fmt.Errorf("peer %q isn't name=ip:port", peer)
+		}
+		ip, port, err := net.SplitHostPort(peerSplit[1])
+		if err != nil {
+			return peersParsed, fmt.Errorf("peer %q isn't name=ip:port : %w", peer, err)
+		}
+		peersParsed = append(peersParsed, peerType{
+			Name: peerSplit[0],
+			IP:   ip,
+			Port: port,
+		})
+	}
+
+	return peersParsed, nil
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"testing"
+)
+
+// TestMain : flags with default values of main() (state in a temporary directory), keepalived v2.0.20
+// and commands recorded by a fakeExecutor.
+func TestMain(m *testing.M) {
+	state, err := ioutil.TempDir("", "lvsnetwork-api-state")
+	if err != nil {
+		log.Fatal(err)
+	}
+	testFlags(state)
+	keepalivedVersion = "v2.0.20"
+	executor = newFakeExecutor()
+	code := m.Run()
+	os.RemoveAll(state)
+	os.Exit(code)
+}
+
+func testString(value string) *string {
+	return &value
+}
+
+func testBool(value bool) *bool {
+	return &value
+}
+
+func testInt(value int) *int {
+	return &value
+}
+
+// testFlags : set flags as main() with default values.
+func testFlags(state string) {
+	configFile = testString("")
+	auditLogFile = testString("")
+	htpasswdfile = testString("")
+	isSlave = testBool(false)
+	listenIPSlave = testString("127.0.0.1")
+	listenPortSlave = testString("8080")
+	httpsSlave = testBool(false)
+	readyTimeout = testInt(5)
+	keepalivedPidFile = testString("/var/run/keepalived.pid")
+	keepalivedDataFile = testString("/tmp/keepalived.data")
+	reloadKeepalivedCommand = testString("/etc/init.d/keepalived-vrrp reload")
+	debug = testBool(false)
+	nodeName = testString("")
+	stateDir = testString(state)
+	jobsTTL = testInt(168)
+	ifaceDir = testString("/etc/network/interfaces.d/")
+	keepalivedDir = testString("/etc/keepalived/keepalived-vrrp.d/")
+	rootDir = testString("")
+	notifyCommand = testString("")
+	webhooks = testString("")
+	peers = []peerType{{Name: "slave", IP: *listenIPSlave, Port: *listenPortSlave}}
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	auth "github.com/abbot/go-http-auth"
+	"github.com/gorilla/mux"
+)
+
+// maintenancePrio : priority of vrrp instances on node in maintenance (lowest priority).
+const maintenancePrio = "1"
+
+// maintenanceDir : directory for save priorities of nodes in maintenance.
+func maintenanceDir() string {
+	return filepath.Join(*stateDir, "maintenance")
+}
+
+// maintenanceFilePath : file with priorities saved before maintenance of node.
+func maintenanceFilePath(node string) string {
+	return filepath.Join(maintenanceDir(), strings.Join([]string{node, ".json"}, ""))
+}
+
+// readMaintenances : read nodes in maintenance with priorities saved (sorted by node),
+// a file not readable keeps its node in maintenance without priorities and with the error.
+func readMaintenances() ([]maintenanceType, error) {
+	maintenances := make([]maintenanceType, 0)
+	files, err := filepath.Glob(filepath.Join(maintenanceDir(), "*.json"))
+	if err != nil {
+		return maintenances, err
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		var maintenance maintenanceType
+		maintenanceByte, err := ioutil.ReadFile(file)
+		if err == nil {
+			err = json.Unmarshal(maintenanceByte, &maintenance)
+		}
+		if err != nil {
+			log.Printf("maintenance file %v : %v", file, err)
+			maintenance = maintenanceType{
+				Node:  strings.TrimSuffix(filepath.Base(file), ".json"),
+				Prios: make(map[string]string),
+				Error: err.Error(),
+			}
+		}
+		maintenances = append(maintenances, maintenance)
+	}
+
+	return maintenances, nil
+}
+
+// saveMaintenance : write priorities before maintenance of node in state directory.
+func saveMaintenance(maintenance maintenanceType) error {
+	err := os.MkdirAll(maintenanceDir(), os.FileMode(permissionFileCreated))
+	if err != nil {
+		return err
+	}
+	maintenanceByte, err := json.Marshal(maintenance)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(maintenanceFilePath(maintenance.Node), maintenanceByte, 0o644)
+}
+
+// removeMaintenance : remove priorities saved for maintenance of node.
+func removeMaintenance(node string) error {
+	err := os.Remove(maintenanceFilePath(node))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// nodesInMaintenance : names of nodes in maintenance.
+func nodesInMaintenance() ([]string, error) {
+	maintenances, err := readMaintenances()
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]string, 0, len(maintenances))
+	for _, maintenance := range maintenances {
+		nodes = append(nodes, maintenance.Node)
+	}
+
+	return nodes, nil
+}
+
+// mutatingRequest : request which changes configuration files or state of nodes (not in dry run,
+// all these requests support dry_run).
+func mutatingRequest(r *http.Request) bool {
+	if r.URL.Query().Get("dry_run") == "true" {
+		return false
+	}
+	if strings.HasPrefix(r.URL.Path, "/v2/") {
+		return r.Method != http.MethodGet && r.Method != http.MethodHead && !strings.HasSuffix(r.URL.Path, "/diff")
+	}
+	for _, prefix := range []string{
+		"/add_", "/remove_", "/change_", "/moveid_", "/drain_", "/restore/", "/failover/",
+		"/enter_maintenance/", "/exit_maintenance/",
+	} {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// maintenanceRequest : request which puts a node in maintenance or exits it (allowed while a node is in maintenance).
+func maintenanceRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/enter_maintenance/") || strings.HasPrefix(r.URL.Path, "/exit_maintenance/")
+}
+
+// maintenanceMiddleware : add header X-Maintenance with nodes in maintenance
+// and refuse requests which change configuration while a node is in maintenance.
+func maintenanceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nodes, err := nodesInMaintenance()
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+		if len(nodes) != 0 {
+			w.Header().Set("X-Maintenance", strings.Join(nodes, ","))
+			if mutatingRequest(r) && !maintenanceRequest(r) {
+				w.WriteHeader(http.StatusConflict)
+				fmt.Fprintln(w, "node in maintenance :", strings.Join(nodes, ", "))
+
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// listMaintenance : on master API for list nodes in maintenance with priorities saved, without body.
+func listMaintenance(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	maintenances, err := readMaintenances()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	js, err := json.Marshal(maintenances)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// enterMaintenance : on master API for put node in maintenance, without body :
+// save priorities, set lowest priority on all vrrp instances of node and wait node isn't MASTER (VIPs on other nodes).
+func enterMaintenance(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	vars := mux.Vars(r)
+	nodes := nodesOps()
+	var node nodeOpsType
+	for _, nodeOps := range nodes {
+		if nodeOps.name == vars["node"] {
+			node = nodeOps
+		}
+	}
+	if node.name == "" {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(w, "unknown node", vars["node"])
+
+		return
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	nodesMaintenance, err := nodesInMaintenance()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	if stringInSlice(node.name, nodesMaintenance) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, "node", node.name, "already in maintenance")
+
+		return
+	}
+	if len(nodesMaintenance)+1 >= len(nodes) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, "no node left out of maintenance for VIPs")
+
+		return
+	}
+	vrrpStates, err := readVrrpStatesMasterSlave()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	maintenance := maintenanceType{
+		Node:  node.name,
+		Date:  time.Now().UTC().Format(time.RFC3339),
+		Prios: make(map[string]string),
+	}
+	var instances []vrrpStateType
+	var ifaceVrrps []ifaceVrrpType
+	for _, instance := range vrrpStates {
+		nodeState, ok := instance.Nodes[node.name]
+		if !ok {
+			continue
+		}
+		instances = append(instances, instance)
+		maintenance.Prios[vrrpStateKey(instance)] = nodeState.Prio
+		if nodeState.Prio == maintenancePrio {
+			continue
+		}
+		ifaceVrrp, err := vrrpWithPrio(node, instance, maintenancePrio)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+		ifaceVrrps = append(ifaceVrrps, ifaceVrrp)
+	}
+	plan := planType{dryRun: r.URL.Query().Get("dry_run") == "true"}
+	if len(ifaceVrrps) != 0 {
+		err = plan.changeVrrpPriosSteps(node, ifaceVrrps)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+	}
+	plan.waitVrrpNotMasterStep(node, instances)
+	plan.add("save priorities",
+		func() error {
+			return saveMaintenance(maintenance)
+		},
+		func() error {
+			return removeMaintenance(maintenance.Node)
+		})
+	runPlan(w, r, &plan)
+}
+
+// exitMaintenance : on master API for exit node of maintenance, without body :
+// set priorities saved on vrrp instances of node.
+func exitMaintenance(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	vars := mux.Vars(r)
+	var node nodeOpsType
+	for _, nodeOps := range nodesOps() {
+		if nodeOps.name == vars["node"] {
+			node = nodeOps
+		}
+	}
+	if node.name == "" {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(w, "unknown node", vars["node"])
+
+		return
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	maintenances, err := readMaintenances()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	var maintenance maintenanceType
+	for _, maintenanceNode := range maintenances {
+		if maintenanceNode.Node == node.name {
+			maintenance = maintenanceNode
+		}
+	}
+	if maintenance.Node == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, "node", node.name, "not in maintenance")
+
+		return
+	}
+	vrrpStates, err := readVrrpStatesMasterSlave()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	var ifaceVrrps []ifaceVrrpType
+	for _, instance := range vrrpStates {
+		nodeState, ok := instance.Nodes[node.name]
+		if !ok {
+			continue
+		}
+		prio, ok := maintenance.Prios[vrrpStateKey(instance)]
+		if !ok || prio == nodeState.Prio {
+			continue
+		}
+		ifaceVrrp, err := vrrpWithPrio(node, instance, prio)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+		ifaceVrrps = append(ifaceVrrps, ifaceVrrp)
+	}
+	plan := planType{dryRun: r.URL.Query().Get("dry_run") == "true"}
+	if len(ifaceVrrps) != 0 {
+		err = plan.changeVrrpPriosSteps(node, ifaceVrrps)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+	}
+	plan.add("remove saved priorities",
+		func() error {
+			return removeMaintenance(maintenance.Node)
+		},
+		func() error {
+			return saveMaintenance(maintenance)
+		})
+	runPlan(w, r, &plan)
+}
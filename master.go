@@ -5,110 +5,201 @@ import (
 	"fmt"
 	"net"
 	"net/http"
-	"os/exec"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	auth "github.com/abbot/go-http-auth"
 	"github.com/gorilla/mux"
 	"golang.org/x/mod/semver"
 )
 
-// checkVlanCom : ping IP 'ipslave' (in json) from master server (check if L2 ok).
+// checkVlanCom : ping IP of each peer ('IP_slave' or 'IP_nodes' in json) from master server until it answers
+// or -ready_timeout (check if L2 ok).
 func checkVlanCom(ifaceVrrp ifaceVrrpType) error {
-	sleep()
-	if strings.Contains(ifaceVrrp.IPSlave, ":") {
-		err := exec.Command("ping6", "-c1", "-t1", ifaceVrrp.IPSlave).Run()
-		if err != nil {
-			return fmt.Errorf("master don't ping slave %v", ifaceVrrp.IPSlave)
-		}
-	} else {
-		err := exec.Command("ping", "-c1", "-t1", ifaceVrrp.IPSlave).Run()
+	for _, peer := range peers {
+		ipPeer := peerIP(ifaceVrrp, peer)
+		ping := "ping"
+		if strings.Contains(ipPeer, ":") {
+			ping = "ping6"
+		}
+		err := pollUntil(strings.Join([]string{"communication with", peer.Name}, " "), func() (bool, string) {
+			_, err := executor.run(ping, "-c1", "-t1", ipPeer)
+			if err != nil {
+				return false, fmt.Sprintf("master don't ping %v %v", peer.Name, ipPeer)
+			}
+
+			return true, ""
+		})
 		if err != nil {
-			return fmt.Errorf("master don't ping slave %v", ifaceVrrp.IPSlave)
+			return err
 		}
 	}
 
 	return nil
 }
 
-// validate missing or incompatibility parameters.
-func (ifaceVrrp ifaceVrrpType) validate() string {
+// checkerForbiddenChars : characters which end value of path or digest in keepalived configuration.
+const checkerForbiddenChars = " \t\"{}\n\r"
+
+const virtualServerNameMessage = "name must contain only letters, digits, '_', '.' and '-'"
+
+var virtualServerNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+const (
+	validationRequired     = "required"
+	validationInvalid      = "invalid"
+	validationOutOfRange   = "out_of_range"
+	validationNotInNetwork = "not_in_network"
+	validationDuplicate    = "duplicate"
+)
+
+// appendValidationError : add error on field of json, unless field already has an error with this code.
+func appendValidationError(errors []validationErrorType, field, code, message string) []validationErrorType {
+	for _, validationError := range errors {
+		if validationError.Field == field && validationError.Code == code {
+			return errors
+		}
+	}
+
+	return append(errors, validationErrorType{
+		Field:   field,
+		Code:    code,
+		Message: message,
+	})
+}
+
+// prefixValidationErrors : errors of a sub-object with path of sub-object before field.
+func prefixValidationErrors(prefix string, errors []validationErrorType) []validationErrorType {
+	for i := range errors {
+		errors[i].Field = strings.Join([]string{prefix, errors[i].Field}, ".")
+	}
+
+	return errors
+}
+
+// sortedKeys : keys of map sorted (errors in same order on each request).
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// writeValidationErrors : response 400 with all errors of validate() in json.
+func writeValidationErrors(w http.ResponseWriter, errors []validationErrorType) {
+	js, err := json.Marshal(validationErrorsType{Errors: errors})
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_, _ = w.Write(js)
+}
+
+// validate missing or incompatibility parameters, all errors are returned.
+func (ifaceVrrp ifaceVrrpType) validate() []validationErrorType {
+	var errors []validationErrorType
 	if !ifaceVrrp.IPVipOnly && len(ifaceVrrp.IPVip) != 0 {
 		if ifaceVrrp.IPMaster == "" {
-			return "missing IP_master"
+			errors = appendValidationError(errors, "IP_master", validationRequired, "missing IP_master")
 		}
 		if ifaceVrrp.IPSlave == "" {
-			return "missing IP_slave"
+			errors = appendValidationError(errors, "IP_slave", validationRequired, "missing IP_slave")
 		}
 		if ifaceVrrp.Mask == "" {
-			return "missing Mask"
+			errors = appendValidationError(errors, "Mask", validationRequired, "missing Mask")
 		}
 		if (strings.Contains(ifaceVrrp.Iface, "vlan")) && (ifaceVrrp.VlanDevice == "") {
-			return "missing Vlan_device with iface vlan"
+			errors = appendValidationError(errors, "Vlan_device", validationRequired, "missing Vlan_device with iface vlan")
 		}
 	}
+	var ipnet *net.IPNet
 	if ifaceVrrp.IPMaster != "" {
 		if ifaceVrrp.Mask == "" {
-			return "missing Mask"
-		}
-		_, ipnet, err := net.ParseCIDR(strings.Join([]string{ifaceVrrp.IPMaster, "/", ifaceVrrp.Mask}, ""))
-		if err != nil {
-			return strings.Join([]string{"Error CIDR ", ifaceVrrp.IPMaster, "/", ifaceVrrp.Mask}, "")
+			errors = appendValidationError(errors, "Mask", validationRequired, "missing Mask")
+		} else {
+			var err error
+			_, ipnet, err = net.ParseCIDR(strings.Join([]string{ifaceVrrp.IPMaster, "/", ifaceVrrp.Mask}, ""))
+			if err != nil {
+				errors = appendValidationError(errors, "IP_master", validationInvalid,
+					strings.Join([]string{"Error CIDR ", ifaceVrrp.IPMaster, "/", ifaceVrrp.Mask}, ""))
+			}
 		}
-		if ifaceVrrp.IPSlave != "" {
-			if !ipnet.Contains(net.ParseIP(ifaceVrrp.IPSlave)) {
-				return strings.Join([]string{"IP_master network don't include IP slave : ", ifaceVrrp.IPSlave}, "")
+		if ifaceVrrp.IPSlave == "" {
+			errors = appendValidationError(errors, "IP_slave", validationRequired, "missing IP_slave")
+		} else if ipnet != nil && !ipnet.Contains(net.ParseIP(ifaceVrrp.IPSlave)) {
+			errors = appendValidationError(errors, "IP_slave", validationNotInNetwork,
+				strings.Join([]string{"IP_master network don't include IP slave : ", ifaceVrrp.IPSlave}, ""))
+		}
+		if ipnet != nil {
+			for _, node := range sortedKeys(ifaceVrrp.IPNodes) {
+				if !ipnet.Contains(net.ParseIP(ifaceVrrp.IPNodes[node])) {
+					errors = appendValidationError(errors, strings.Join([]string{"IP_nodes.", node}, ""), validationNotInNetwork,
+						strings.Join([]string{"IP_master network don't include IP of node ", node, " : ",
+							ifaceVrrp.IPNodes[node]}, ""))
+				}
 			}
-		} else {
-			return "missing IP_slave"
 		}
 	}
 	if (ifaceVrrp.DefaultGW != "") && (ifaceVrrp.IPMaster == "" || ifaceVrrp.IPSlave == "") {
-		return "missing IP_master || IP_slave with Default_GW"
+		errors = appendValidationError(errors, "Default_GW", validationRequired,
+			"missing IP_master || IP_slave with Default_GW")
 	}
-	if len(ifaceVrrp.IPVip) != 0 && !ifaceVrrp.IPVipOnly {
-		for _, vip := range ifaceVrrp.IPVip {
-			_, ipnet, _ := net.ParseCIDR(strings.Join([]string{ifaceVrrp.IPMaster, "/", ifaceVrrp.Mask}, ""))
+	if len(ifaceVrrp.IPVip) != 0 && !ifaceVrrp.IPVipOnly && ipnet != nil {
+		for i, vip := range ifaceVrrp.IPVip {
 			if !ipnet.Contains(net.ParseIP(vip)) {
-				return strings.Join([]string{"IP_master network don't include VIP : ", vip}, "")
+				errors = appendValidationError(errors, fmt.Sprintf("IP_vip[%d]", i), validationNotInNetwork,
+					strings.Join([]string{"IP_master network don't include VIP : ", vip}, ""))
 			}
 		}
 	}
 	if len(ifaceVrrp.IPVip) != 0 {
 		if ifaceVrrp.VrrpGroup == "" {
-			return "missing Vrrp_group for VIP"
+			errors = appendValidationError(errors, "Vrrp_group", validationRequired, "missing Vrrp_group for VIP")
 		}
 		if ifaceVrrp.IDVrrp == "" {
-			return "missing ID_vrrp for VIP"
-		}
-		IDVrrpInt, err := strconv.Atoi(ifaceVrrp.IDVrrp)
-		if err != nil {
-			return "Error on Id_vrrp integer"
-		}
-		if IDVrrpInt < 1 || IDVrrpInt > 255 {
-			return "Id_vrrp must be in the range from 1 to 255"
+			errors = appendValidationError(errors, "Id_vrrp", validationRequired, "missing ID_vrrp for VIP")
+		} else if IDVrrpInt, err := strconv.Atoi(ifaceVrrp.IDVrrp); err != nil {
+			errors = appendValidationError(errors, "Id_vrrp", validationInvalid, "Error on Id_vrrp integer")
+		} else if IDVrrpInt < 1 || IDVrrpInt > 255 {
+			errors = appendValidationError(errors, "Id_vrrp", validationOutOfRange,
+				"Id_vrrp must be in the range from 1 to 255")
 		}
 		if ifaceVrrp.PrioMaster == "" {
-			return "missing Prio_master for VIP"
+			errors = appendValidationError(errors, "Prio_master", validationRequired, "missing Prio_master for VIP")
 		}
 		if ifaceVrrp.PrioSlave == "" {
-			return "missing Prio_slave for VIP"
+			errors = appendValidationError(errors, "Prio_slave", validationRequired, "missing Prio_slave for VIP")
+		}
+		for _, node := range sortedKeys(ifaceVrrp.PrioNodes) {
+			if _, err := strconv.Atoi(ifaceVrrp.PrioNodes[node]); err != nil {
+				errors = appendValidationError(errors, strings.Join([]string{"Prio_nodes.", node}, ""), validationInvalid,
+					strings.Join([]string{"Error on Prio_nodes integer for node ", node}, ""))
+			}
 		}
 	}
-	if ((ifaceVrrp.AuthType != "") && (ifaceVrrp.AuthPass == "")) ||
-		((ifaceVrrp.AuthPass != "") && (ifaceVrrp.AuthType == "")) {
-		return "missing Auth_type or Auth_pass"
+	if (ifaceVrrp.AuthType != "") && (ifaceVrrp.AuthPass == "") {
+		errors = appendValidationError(errors, "Auth_pass", validationRequired, "missing Auth_type or Auth_pass")
+	}
+	if (ifaceVrrp.AuthPass != "") && (ifaceVrrp.AuthType == "") {
+		errors = appendValidationError(errors, "Auth_type", validationRequired, "missing Auth_type or Auth_pass")
 	}
 
-	return ""
+	return errors
 }
 
 // addIfaceVrrp : on master API for add configuration (network + vrrp) on master & slave server.
 func addIfaceVrrp(w http.ResponseWriter, r *http.Request) {
-	if *htpasswdfile != "" {
-		htpasswd := auth.HtpasswdFileProvider(*htpasswdfile)
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
 		authenticator := auth.BasicAuth{
 			Realm:   "Basic Realm",
 			Secrets: htpasswd,
@@ -141,13 +232,15 @@ func addIfaceVrrp(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	validate := ifaceVrrp.validate()
-	if validate != "" {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintln(w, validate)
+	validationErrors := ifaceVrrp.validate()
+	if len(validationErrors) != 0 {
+		writeValidationErrors(w, validationErrors)
 
 		return
 	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	plan := planType{dryRun: r.URL.Query().Get("dry_run") == "true"}
 	// iface configuration
 	if !ifaceVrrp.IPVipOnly {
 		ifaceExistsMaster := checkIfaceExists(ifaceVrrp)
@@ -166,36 +259,30 @@ func addIfaceVrrp(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		ifaceExistsSlave, err := checkIfaceSlaveExists(ifaceVrrp)
-		if err != nil {
-			http.Error(w, err.Error(), 500)
-
-			return
-		}
-		if ifaceExistsSlave {
-			ifaceOkSlave, err := checkIfaceSlaveOk(ifaceVrrp)
+		for _, peer := range peers {
+			ifaceExistsPeer, err := checkIfacePeerExists(peer, ifaceVrrp)
 			if err != nil {
 				http.Error(w, err.Error(), 500)
 
 				return
 			}
-			if !ifaceOkSlave {
-				w.WriteHeader(http.StatusBadRequest)
-				fmt.Fprintln(w, "iface already exist on slave with different config or not up")
+			if ifaceExistsPeer {
+				ifaceOkPeer, err := checkIfacePeerOk(peer, ifaceVrrp)
+				if err != nil {
+					http.Error(w, err.Error(), 500)
 
-				return
-			}
-		}
-		if !ifaceExistsMaster {
-			err := addIface(ifaceVrrp)
-			if err != nil {
-				http.Error(w, err.Error(), 500)
+					return
+				}
+				if !ifaceOkPeer {
+					w.WriteHeader(http.StatusBadRequest)
+					fmt.Fprintln(w, "iface already exist on slave", peer.Name, "with different config or not up")
 
-				return
+					return
+				}
 			}
 		}
-		if !ifaceExistsSlave {
-			err := addIfaceSlave(ifaceVrrp)
+		for _, node := range nodesOps() {
+			err := plan.addIfaceSteps(node, ifaceVrrp)
 			if err != nil {
 				http.Error(w, err.Error(), 500)
 
@@ -203,25 +290,7 @@ func addIfaceVrrp(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		if ifaceVrrp.IPMaster != "" {
-			err = checkVlanCom(ifaceVrrp)
-			if err != nil {
-				sleep()
-				err2 := checkVlanCom(ifaceVrrp)
-				if err2 != nil {
-					errReturn := fmt.Errorf("%v %v", err, err2) // nolint: errorlint
-					err3 := removeIfaceSlave(ifaceVrrp)
-					if err3 != nil {
-						errReturn = fmt.Errorf("%v %v", errReturn, err3) // nolint: errorlint
-					}
-					err3 = removeIface(ifaceVrrp)
-					if err3 != nil {
-						errReturn = fmt.Errorf("%v %v", errReturn, err3) // nolint: errorlint
-					}
-					http.Error(w, errReturn.Error(), 500)
-
-					return
-				}
-			}
+			plan.checkVlanComStep(ifaceVrrp)
 		}
 	} else {
 		if !checkIfaceExists(ifaceVrrp) {
@@ -230,119 +299,75 @@ func addIfaceVrrp(w http.ResponseWriter, r *http.Request) {
 
 			return
 		}
-		ifaceExistsSlave, err := checkIfaceSlaveExists(ifaceVrrp)
-		if err != nil {
-			http.Error(w, err.Error(), 500)
+		for _, peer := range peers {
+			ifaceExistsPeer, err := checkIfacePeerExists(peer, ifaceVrrp)
+			if err != nil {
+				http.Error(w, err.Error(), 500)
 
-			return
-		}
-		if !ifaceExistsSlave {
-			w.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintln(w, "Iface", ifaceVrrp.Iface, "does not exist on slave")
+				return
+			}
+			if !ifaceExistsPeer {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintln(w, "Iface", ifaceVrrp.Iface, "does not exist on slave", peer.Name)
 
-			return
+				return
+			}
 		}
 	}
 	// vrrp configuration
 	if len(ifaceVrrp.IPVip) != 0 {
-		mutex.Lock()
-		addIfaceVrrpKeepalived(ifaceVrrp, true, w)
-		sleep()
-
-		addIfaceVrrpKeepalived(ifaceVrrp, false, w)
-		sleep()
-
-		mutex.Unlock()
-	}
-}
-
-func addIfaceVrrpKeepalived(ifaceVrrp ifaceVrrpType, master bool, w http.ResponseWriter) {
-	var err error
-	var vrrpExists bool
-	if master {
-		vrrpExists = checkVrrpExists(ifaceVrrp)
-	} else {
-		vrrpExists, err = checkVrrpSlaveExists(ifaceVrrp)
-		if err != nil {
-			http.Error(w, err.Error(), 500)
-
-			return
-		}
-	}
-	if vrrpExists {
-		var vrrpOk bool
-		if master {
-			vrrpOk, err = checkVrrpOk(ifaceVrrp)
-		} else {
-			vrrpOk, err = checkVrrpSlaveOk(ifaceVrrp)
-		}
-		if err != nil {
-			http.Error(w, err.Error(), 500)
+		if checkVrrpExists(ifaceVrrp) {
+			vrrpOkMaster, err := checkVrrpOk(ifaceVrrp)
+			if err != nil {
+				http.Error(w, err.Error(), 500)
 
-			return
-		}
-		if !vrrpOk {
-			w.WriteHeader(http.StatusBadRequest)
-			if master {
-				fmt.Fprintln(w, "vrrp already exist on master with different config")
-			} else {
-				fmt.Fprintln(w, "vrrp already exist on slave with different config")
+				return
 			}
+			if !vrrpOkMaster {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintln(w, "vrrp already exist on master with different config")
 
-			return
-		}
-		if master {
-			err = syncGroupAndReload()
-		} else {
-			err = syncGroupAndReloadSlave()
-		}
-		if err != nil {
-			http.Error(w, err.Error(), 500)
-
-			return
-		}
-	} else {
-		if master {
-			err = addVrrp(ifaceVrrp)
-		} else {
-			err = addVrrpSlave(ifaceVrrp)
+				return
+			}
 		}
-		if err != nil {
-			http.Error(w, err.Error(), 500)
+		for _, peer := range peers {
+			vrrpExistsPeer, err := checkVrrpPeerExists(peer, ifaceVrrp)
+			if err != nil {
+				http.Error(w, err.Error(), 500)
 
-			return
-		}
-		if master {
-			err = reloadVrrp()
-		} else {
-			err = reloadVrrpSlave()
-		}
-		if err != nil {
-			http.Error(w, err.Error(), 500)
+				return
+			}
+			if vrrpExistsPeer {
+				vrrpOkPeer, err := checkVrrpPeerOk(peer, ifaceVrrp)
+				if err != nil {
+					http.Error(w, err.Error(), 500)
 
-			return
-		}
+					return
+				}
+				if !vrrpOkPeer {
+					w.WriteHeader(http.StatusBadRequest)
+					fmt.Fprintln(w, "vrrp already exist on slave", peer.Name, "with different config")
 
-		// reload twice for vmac up before add IP (bug keepalived)
-		// reload twice for new vrrp comme up
-		sleep()
-		if master {
-			err = syncGroupAndReload()
-		} else {
-			err = syncGroupAndReloadSlave()
+					return
+				}
+			}
 		}
-		if err != nil {
-			http.Error(w, err.Error(), 500)
+		for _, node := range nodesOps() {
+			err := plan.addVrrpSteps(node, ifaceVrrp)
+			if err != nil {
+				http.Error(w, err.Error(), 500)
 
-			return
+				return
+			}
 		}
 	}
+	runPlan(w, r, &plan)
 }
 
 // removeIfaceVrrp on master API for remove all configuration (network + vrrp) on master & slave server.
 func removeIfaceVrrp(w http.ResponseWriter, r *http.Request) {
-	if *htpasswdfile != "" {
-		htpasswd := auth.HtpasswdFileProvider(*htpasswdfile)
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
 		authenticator := auth.BasicAuth{
 			Realm:   "Basic Realm",
 			Secrets: htpasswd,
@@ -375,111 +400,46 @@ func removeIfaceVrrp(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	validate := ifaceVrrp.validate()
-	if validate != "" {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintln(w, validate)
+	validationErrors := ifaceVrrp.validate()
+	if len(validationErrors) != 0 {
+		writeValidationErrors(w, validationErrors)
 
 		return
 	}
 	mutex.Lock()
+	defer mutex.Unlock()
+	plan := planType{dryRun: r.URL.Query().Get("dry_run") == "true"}
+	// remove on slave peers before master
+	nodes := nodesOps()
 	// vrrp configuration
 	if len(ifaceVrrp.IPVip) != 0 {
-		vrrpExistsSlave, err := checkVrrpSlaveExists(ifaceVrrp)
-		if err != nil {
-			http.Error(w, err.Error(), 500)
-			mutex.Unlock()
-
-			return
-		}
-		if vrrpExistsSlave {
-			err := removeVrrpSlave(ifaceVrrp)
-			if err != nil {
-				http.Error(w, err.Error(), 500)
-				mutex.Unlock()
-
-				return
-			}
-			err = syncGroupAndReloadSlave()
-			if err != nil {
-				http.Error(w, err.Error(), 500)
-				mutex.Unlock()
-
-				return
-			}
-		} else {
-			err := syncGroupAndReloadSlave()
-			if err != nil {
-				http.Error(w, err.Error(), 500)
-				mutex.Unlock()
-
-				return
-			}
-		}
-		sleep()
-
-		vrrpExistsMaster := checkVrrpExists(ifaceVrrp)
-		if vrrpExistsMaster {
-			err := removeVrrp(ifaceVrrp)
-			if err != nil {
-				http.Error(w, err.Error(), 500)
-				mutex.Unlock()
-
-				return
-			}
-			err = syncGroupAndReload()
-			if err != nil {
-				http.Error(w, err.Error(), 500)
-				mutex.Unlock()
-
-				return
-			}
-		} else {
-			err := syncGroupAndReload()
+		for i := len(nodes) - 1; i >= 0; i-- {
+			err = plan.removeVrrpSteps(nodes[i], ifaceVrrp)
 			if err != nil {
 				http.Error(w, err.Error(), 500)
-				mutex.Unlock()
 
 				return
 			}
 		}
-		sleep()
 	}
 	// iface configuration
 	if !ifaceVrrp.IPVipOnly {
-		ifaceExistsSlave, err := checkIfaceSlaveExists(ifaceVrrp)
-		if err != nil {
-			http.Error(w, err.Error(), 500)
-			mutex.Unlock()
-
-			return
-		}
-		if ifaceExistsSlave {
-			err := removeIfaceSlave(ifaceVrrp)
-			if err != nil {
-				http.Error(w, err.Error(), 500)
-				mutex.Unlock()
-
-				return
-			}
-		}
-		if checkIfaceExists(ifaceVrrp) {
-			err := removeIface(ifaceVrrp)
+		for i := len(nodes) - 1; i >= 0; i-- {
+			err = plan.removeIfaceSteps(nodes[i], ifaceVrrp)
 			if err != nil {
 				http.Error(w, err.Error(), 500)
-				mutex.Unlock()
 
 				return
 			}
 		}
 	}
-	mutex.Unlock()
+	runPlan(w, r, &plan)
 }
 
 // checkIfaceVrrp on master API for check all configuration (network + vrrp) on master & slave server.
 func checkIfaceVrrp(w http.ResponseWriter, r *http.Request) {
-	if *htpasswdfile != "" {
-		htpasswd := auth.HtpasswdFileProvider(*htpasswdfile)
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
 		authenticator := auth.BasicAuth{
 			Realm:   "Basic Realm",
 			Secrets: htpasswd,
@@ -511,10 +471,9 @@ func checkIfaceVrrp(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	validate := ifaceVrrp.validate()
-	if validate != "" {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintln(w, validate)
+	validationErrors := ifaceVrrp.validate()
+	if len(validationErrors) != 0 {
+		writeValidationErrors(w, validationErrors)
 
 		return
 	}
@@ -529,24 +488,19 @@ func checkIfaceVrrp(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 			if !ifaceOkMaster {
-				ifaceOkWithoutPostup, err := checkIfaceWithoutPostup(ifaceVrrp)
+				ifaceReadMaster, err := readIfaceFile(ifaceVrrp.Iface)
 				if err != nil {
 					http.Error(w, err.Error(), 500)
 
 					return
 				}
-				if !ifaceOkWithoutPostup {
-					w.WriteHeader(http.StatusPartialContent)
-					ifaceVrrpResponse.IPMaster = "?"
-					ifaceVrrpResponse.Mask = "?"
-					ifaceVrrpResponse.PostUp = []string{"?"}
-					ifaceVrrpResponse.DefaultGW = ""
-					ifaceVrrpResponse.LACPSlavesMaster = ""
-					ifaceVrrpResponse.VlanDevice = ""
-				} else {
-					w.WriteHeader(http.StatusPartialContent)
-					ifaceVrrpResponse.PostUp = []string{"?"}
-				}
+				w.WriteHeader(http.StatusPartialContent)
+				ifaceVrrpResponse.IPMaster = ifaceReadMaster.IPMaster
+				ifaceVrrpResponse.Mask = ifaceReadMaster.Mask
+				ifaceVrrpResponse.PostUp = ifaceReadMaster.PostUp
+				ifaceVrrpResponse.DefaultGW = ifaceReadMaster.DefaultGW
+				ifaceVrrpResponse.LACPSlavesMaster = ifaceReadMaster.LACPSlavesMaster
+				ifaceVrrpResponse.VlanDevice = ifaceReadMaster.VlanDevice
 			}
 		} else {
 			ifaceExistsSlave, err := checkIfaceSlaveExists(ifaceVrrp)
@@ -563,25 +517,24 @@ func checkIfaceVrrp(w http.ResponseWriter, r *http.Request) {
 					return
 				}
 				if !ifaceOkSlave {
-					ifaceOkWithoutPostup, err := checkIfaceSlaveWithoutPostup(ifaceVrrp)
-					if err != nil {
-						http.Error(w, err.Error(), 500)
+					for _, peer := range peers {
+						ifaceReadSlave, _, err := readIfaceVrrpPeer(peer, ifaceVrrp.Iface)
+						if err != nil {
+							http.Error(w, err.Error(), 500)
 
-						return
-					}
-					if !ifaceOkWithoutPostup {
-						w.WriteHeader(http.StatusPartialContent)
-						ifaceVrrpResponse.IPSlave = "?"
-						ifaceVrrpResponse.LACPSlavesSlave = ""
-					} else {
-						w.WriteHeader(http.StatusPartialContent)
-						ifaceVrrpResponse.PostUp = []string{"?"}
+							return
+						}
+						fillPeerIP(&ifaceVrrpResponse, peer, ifaceReadSlave.IPSlave)
+						ifaceVrrpResponse.Mask = ifaceReadSlave.Mask
+						ifaceVrrpResponse.PostUp = ifaceReadSlave.PostUp
+						ifaceVrrpResponse.DefaultGW = ifaceReadSlave.DefaultGW
+						ifaceVrrpResponse.LACPSlavesSlave = ifaceReadSlave.LACPSlavesSlave
+						ifaceVrrpResponse.VlanDevice = ifaceReadSlave.VlanDevice
 					}
-				} else {
-					w.WriteHeader(http.StatusPartialContent)
-					ifaceVrrpResponse.IPMaster = "?"
-					ifaceVrrpResponse.LACPSlavesMaster = ""
 				}
+				w.WriteHeader(http.StatusPartialContent)
+				ifaceVrrpResponse.IPMaster = ""
+				ifaceVrrpResponse.LACPSlavesMaster = ""
 			} else {
 				w.WriteHeader(http.StatusNotFound)
 
@@ -591,36 +544,28 @@ func checkIfaceVrrp(w http.ResponseWriter, r *http.Request) {
 	}
 	// vrrp configuration
 	if len(ifaceVrrp.IPVip) != 0 {
-		vrrpExistsMaster := checkVrrpExists(ifaceVrrp)
-		if vrrpExistsMaster {
-			vrrpOkMaster, err := checkVrrpOk(ifaceVrrp)
+		vrrpOkMaster := false
+		if checkVrrpExists(ifaceVrrp) {
+			vrrpOkMaster, err = checkVrrpOk(ifaceVrrp)
 			if err != nil {
 				http.Error(w, err.Error(), 500)
 
 				return
 			}
-			if !vrrpOkMaster {
-				w.WriteHeader(http.StatusPartialContent)
-				ifaceVrrpResponse.IPVip = []string{"?"}
-				ifaceVrrpResponse.PrioMaster = "?"
-				ifaceVrrpResponse.AuthType = ""
-				ifaceVrrpResponse.AuthPass = ""
-				ifaceVrrpResponse.SyncIface = ""
-				ifaceVrrpResponse.GarpMDelay = ""
-				ifaceVrrpResponse.AdvertInt = ""
+		}
+		if !vrrpOkMaster {
+			vrrpReadMaster, _, err := readIfaceVrrp(ifaceVrrp.Iface)
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+
+				return
 			}
-		} else {
 			w.WriteHeader(http.StatusPartialContent)
-			ifaceVrrpResponse.IPVip = []string{"?"}
-			ifaceVrrpResponse.IDVrrp = "?"
-			ifaceVrrpResponse.PrioMaster = "?"
-			ifaceVrrpResponse.AuthType = ""
-			ifaceVrrpResponse.AuthPass = ""
-			ifaceVrrpResponse.SyncIface = ""
-			ifaceVrrpResponse.GarpMDelay = ""
-			ifaceVrrpResponse.AdvertInt = ""
+			fillVrrpRead(&ifaceVrrpResponse, vrrpReadMaster)
+			ifaceVrrpResponse.PrioMaster = vrrpReadMaster.PrioMaster
 		}
 
+		vrrpOkSlave := false
 		vrrpExistsSlave, err := checkVrrpSlaveExists(ifaceVrrp)
 		if err != nil {
 			http.Error(w, err.Error(), 500)
@@ -628,33 +573,25 @@ func checkIfaceVrrp(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if vrrpExistsSlave {
-			vrrpOkSlave, err := checkVrrpSlaveOk(ifaceVrrp)
+			vrrpOkSlave, err = checkVrrpSlaveOk(ifaceVrrp)
 			if err != nil {
 				http.Error(w, err.Error(), 500)
 
 				return
 			}
-			if !vrrpOkSlave {
-				w.WriteHeader(http.StatusPartialContent)
-				ifaceVrrpResponse.PrioSlave = "?"
-				ifaceVrrpResponse.IPVip = []string{"?"}
-				ifaceVrrpResponse.IDVrrp = "?"
-				ifaceVrrpResponse.AuthType = ""
-				ifaceVrrpResponse.AuthPass = ""
-				ifaceVrrpResponse.SyncIface = ""
-				ifaceVrrpResponse.GarpMDelay = ""
-				ifaceVrrpResponse.AdvertInt = ""
+		}
+		if !vrrpOkSlave {
+			for _, peer := range peers {
+				vrrpReadSlave, _, err := readIfaceVrrpPeer(peer, ifaceVrrp.Iface)
+				if err != nil {
+					http.Error(w, err.Error(), 500)
+
+					return
+				}
+				fillVrrpRead(&ifaceVrrpResponse, vrrpReadSlave)
+				fillPeerPrio(&ifaceVrrpResponse, peer, vrrpReadSlave.PrioSlave)
 			}
-		} else {
 			w.WriteHeader(http.StatusPartialContent)
-			ifaceVrrpResponse.PrioSlave = "?"
-			ifaceVrrpResponse.IPVip = []string{"?"}
-			ifaceVrrpResponse.IDVrrp = "?"
-			ifaceVrrpResponse.AuthType = ""
-			ifaceVrrpResponse.AuthPass = ""
-			ifaceVrrpResponse.SyncIface = ""
-			ifaceVrrpResponse.GarpMDelay = ""
-			ifaceVrrpResponse.AdvertInt = ""
 		}
 	}
 	js, err := json.Marshal(ifaceVrrpResponse)
@@ -674,8 +611,8 @@ func checkIfaceVrrp(w http.ResponseWriter, r *http.Request) {
 
 // changeIfaceVrrp on master API for change configuration needed (network + vrrp) on master & slave server.
 func changeIfaceVrrp(w http.ResponseWriter, r *http.Request) {
-	if *htpasswdfile != "" {
-		htpasswd := auth.HtpasswdFileProvider(*htpasswdfile)
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
 		authenticator := auth.BasicAuth{
 			Realm:   "Basic Realm",
 			Secrets: htpasswd,
@@ -707,13 +644,15 @@ func changeIfaceVrrp(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	validate := ifaceVrrp.validate()
-	if validate != "" {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintln(w, validate)
+	validationErrors := ifaceVrrp.validate()
+	if len(validationErrors) != 0 {
+		writeValidationErrors(w, validationErrors)
 
 		return
 	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	plan := planType{dryRun: r.URL.Query().Get("dry_run") == "true"}
 	// iface configuration
 	if !ifaceVrrp.IPVipOnly {
 		ifaceExistsMaster := checkIfaceExists(ifaceVrrp)
@@ -723,17 +662,19 @@ func changeIfaceVrrp(w http.ResponseWriter, r *http.Request) {
 
 			return
 		}
-		ifaceExistsSlave, err := checkIfaceSlaveExists(ifaceVrrp)
-		if err != nil {
-			http.Error(w, err.Error(), 500)
+		for _, peer := range peers {
+			ifaceExistsPeer, err := checkIfacePeerExists(peer, ifaceVrrp)
+			if err != nil {
+				http.Error(w, err.Error(), 500)
 
-			return
-		}
-		if !ifaceExistsSlave {
-			w.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintln(w, "Iface", ifaceVrrp.Iface, "does not exist on slave")
+				return
+			}
+			if !ifaceExistsPeer {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintln(w, "Iface", ifaceVrrp.Iface, "does not exist on slave", peer.Name)
 
-			return
+				return
+			}
 		}
 		ifaceOkMaster, err := checkIfaceOk(ifaceVrrp)
 		if err != nil {
@@ -755,33 +696,24 @@ func changeIfaceVrrp(w http.ResponseWriter, r *http.Request) {
 
 				return
 			}
-			err = changeIfacePostup(ifaceVrrp)
+			err = plan.changeIfaceSteps(masterOps(), ifaceVrrp)
 			if err != nil {
 				http.Error(w, err.Error(), 500)
 
 				return
 			}
-			err = removeIfaceFile(ifaceVrrp)
+		}
+		for _, peer := range peers {
+			ifaceOkPeer, err := checkIfacePeerOk(peer, ifaceVrrp)
 			if err != nil {
 				http.Error(w, err.Error(), 500)
 
 				return
 			}
-			err = addIfaceFile(ifaceVrrp)
-			if err != nil {
-				http.Error(w, err.Error(), 500)
-
-				return
+			if ifaceOkPeer {
+				continue
 			}
-		}
-		ifaceOkSlave, err := checkIfaceSlaveOk(ifaceVrrp)
-		if err != nil {
-			http.Error(w, err.Error(), 500)
-
-			return
-		}
-		if !ifaceOkSlave {
-			ifaceOkWithoutPostup, err := checkIfaceSlaveWithoutPostup(ifaceVrrp)
+			ifaceOkWithoutPostup, err := checkIfacePeerWithoutPostup(peer, ifaceVrrp)
 			if err != nil {
 				http.Error(w, err.Error(), 500)
 
@@ -789,24 +721,12 @@ func changeIfaceVrrp(w http.ResponseWriter, r *http.Request) {
 			}
 			if !ifaceOkWithoutPostup {
 				w.WriteHeader(http.StatusBadRequest)
-				fmt.Fprintln(w, "[SLAVE] Change IP_master, IP_slave, Mask, Default_GW,"+
+				fmt.Fprintln(w, "[SLAVE "+peer.Name+"] Change IP_master, IP_slave, Mask, Default_GW,"+
 					" LACP_slaves_master, LACP_slaves_slave or Vlan_device isn't possible")
 
 				return
 			}
-			err = changeIfaceSlavePostup(ifaceVrrp)
-			if err != nil {
-				http.Error(w, err.Error(), 500)
-
-				return
-			}
-			err = removeIfaceSlaveFile(ifaceVrrp)
-			if err != nil {
-				http.Error(w, err.Error(), 500)
-
-				return
-			}
-			err = addIfaceSlaveFile(ifaceVrrp)
+			err = plan.changeIfaceSteps(peerOps(peer), ifaceVrrp)
 			if err != nil {
 				http.Error(w, err.Error(), 500)
 
@@ -824,23 +744,8 @@ func changeIfaceVrrp(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		vrrpExistsSlave, err := checkVrrpSlaveExists(ifaceVrrp)
-		if err != nil {
-			http.Error(w, err.Error(), 500)
-
-			return
-		}
-		vrrpExistsSlaveOtherVG, err := checkVrrpSlaveExistsOtherVG(ifaceVrrp)
-		if err != nil {
-			http.Error(w, err.Error(), 500)
-
-			return
-		}
-
 		var vrrpOkMaster bool
-		var vrrpOkSlave bool
 		var ifaceVrrpRmMaster ifaceVrrpType
-		var ifaceVrrpRmSlave ifaceVrrpType
 
 		switch {
 		case vrrpExistsMaster:
@@ -859,190 +764,75 @@ func changeIfaceVrrp(w http.ResponseWriter, r *http.Request) {
 			vrrpOkMaster = false
 		}
 
-		switch {
-		case vrrpExistsSlave:
-			vrrpOkSlave, err = checkVrrpSlaveOk(ifaceVrrp)
-			if err != nil {
-				http.Error(w, err.Error(), 500)
-
-				return
-			}
-			ifaceVrrpRmSlave = ifaceVrrp
-		case vrrpExistsSlaveOtherVG != "":
-			vrrpOkSlave = false
-			ifaceVrrpRmSlave = ifaceVrrp
-			ifaceVrrpRmSlave.VrrpGroup = vrrpExistsSlaveOtherVG
-		default:
-			vrrpOkSlave = false
-		}
-
 		if !vrrpOkMaster {
-			mutex.Lock()
-			if ifaceVrrpRmMaster.VrrpGroup != "" {
-				err = removeVrrp(ifaceVrrpRmMaster)
-				if err != nil {
-					http.Error(w, err.Error(), 500)
-					mutex.Unlock()
-
-					return
-				}
-			}
-			err = addVrrp(ifaceVrrp)
+			err = plan.changeVrrpSteps(masterOps(), ifaceVrrp, ifaceVrrpRmMaster)
 			if err != nil {
 				http.Error(w, err.Error(), 500)
-				mutex.Unlock()
 
 				return
 			}
-			err = reloadVrrp()
+		}
+		// each peer can have the vrrp instance in a different Vrrp_group
+		for _, peer := range peers {
+			vrrpExistsPeer, err := checkVrrpPeerExists(peer, ifaceVrrp)
 			if err != nil {
 				http.Error(w, err.Error(), 500)
-				mutex.Unlock()
 
 				return
 			}
-			// reload twice for vmac up before add IP (bug keepalived)
-			// reload twice for new vrrp comme up
-
-			sleep()
-			err = syncGroupAndReload()
+			vrrpExistsPeerOtherVG, err := checkVrrpPeerExistsOtherVG(peer, ifaceVrrp)
 			if err != nil {
 				http.Error(w, err.Error(), 500)
-				mutex.Unlock()
 
 				return
 			}
-			sleep()
-
-			if !vrrpOkSlave {
-				if ifaceVrrpRmSlave.VrrpGroup != "" {
-					err = removeVrrpSlave(ifaceVrrpRmSlave)
-					if err != nil {
-						http.Error(w, err.Error(), 500)
-
-						return
-					}
-				}
-				err = addVrrpSlave(ifaceVrrp)
-				if err != nil {
-					http.Error(w, err.Error(), 500)
-					mutex.Unlock()
-
-					return
-				}
-				err = syncGroupAndReloadSlave()
+			var vrrpOkPeer bool
+			var ifaceVrrpRmPeer ifaceVrrpType
+			switch {
+			case vrrpExistsPeer:
+				vrrpOkPeer, err = checkVrrpPeerOk(peer, ifaceVrrp)
 				if err != nil {
 					http.Error(w, err.Error(), 500)
-					mutex.Unlock()
 
 					return
 				}
-				// reload twice for vmac up before add IP (bug keepalived)
-				if ifaceVrrp.UseVmac {
-					sleep()
-					err = syncGroupAndReloadSlave()
-					if err != nil {
-						http.Error(w, err.Error(), 500)
-						mutex.Unlock()
-
-						return
-					}
-				}
-				sleep()
+				ifaceVrrpRmPeer = ifaceVrrp
+			case vrrpExistsPeerOtherVG != "":
+				ifaceVrrpRmPeer = ifaceVrrp
+				ifaceVrrpRmPeer.VrrpGroup = vrrpExistsPeerOtherVG
 			}
-			mutex.Unlock()
-		} else if !vrrpOkSlave {
-			mutex.Lock()
-			if ifaceVrrpRmSlave.VrrpGroup != "" {
-				err := removeVrrpSlave(ifaceVrrpRmSlave)
+			if !vrrpOkPeer {
+				err = plan.changeVrrpSteps(peerOps(peer), ifaceVrrp, ifaceVrrpRmPeer)
 				if err != nil {
 					http.Error(w, err.Error(), 500)
-					mutex.Unlock()
 
 					return
 				}
 			}
-			err = addVrrpSlave(ifaceVrrp)
-			if err != nil {
-				http.Error(w, err.Error(), 500)
-				mutex.Unlock()
-
-				return
-			}
-			err = syncGroupAndReloadSlave()
+		}
+	} else {
+		for _, peer := range peers {
+			err = plan.removeVrrpSteps(peerOps(peer), ifaceVrrp)
 			if err != nil {
 				http.Error(w, err.Error(), 500)
-				mutex.Unlock()
 
 				return
 			}
-			// reload twice for vmac up before add IP (bug keepalived)
-			if ifaceVrrp.UseVmac {
-				sleep()
-				err = syncGroupAndReloadSlave()
-				if err != nil {
-					http.Error(w, err.Error(), 500)
-					mutex.Unlock()
-
-					return
-				}
-			}
-			sleep()
-			mutex.Unlock()
 		}
-	} else {
-		mutex.Lock()
-		vrrpExistsSlave, err := checkVrrpSlaveExists(ifaceVrrp)
+		err = plan.removeVrrpSteps(masterOps(), ifaceVrrp)
 		if err != nil {
 			http.Error(w, err.Error(), 500)
-			mutex.Unlock()
 
 			return
 		}
-		if vrrpExistsSlave {
-			err := removeVrrpSlave(ifaceVrrp)
-			if err != nil {
-				http.Error(w, err.Error(), 500)
-				mutex.Unlock()
-
-				return
-			}
-			err = syncGroupAndReloadSlave()
-			if err != nil {
-				http.Error(w, err.Error(), 500)
-				mutex.Unlock()
-
-				return
-			}
-			sleep()
-		}
-
-		vrrpExistsMaster := checkVrrpExists(ifaceVrrp)
-		if vrrpExistsMaster {
-			err := removeVrrp(ifaceVrrp)
-			if err != nil {
-				http.Error(w, err.Error(), 500)
-				mutex.Unlock()
-
-				return
-			}
-			err = syncGroupAndReload()
-			if err != nil {
-				http.Error(w, err.Error(), 500)
-				mutex.Unlock()
-
-				return
-			}
-			sleep()
-		}
-		mutex.Unlock()
 	}
+	runPlan(w, r, &plan)
 }
 
 // moveIDIfaceVrrp on master API for change ID vrrp without vrrp flap on slave.
 func moveIDIfaceVrrp(w http.ResponseWriter, r *http.Request) {
-	if *htpasswdfile != "" {
-		htpasswd := auth.HtpasswdFileProvider(*htpasswdfile)
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
 		authenticator := auth.BasicAuth{
 			Realm:   "Basic Realm",
 			Secrets: htpasswd,
@@ -1075,160 +865,98 @@ func moveIDIfaceVrrp(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	validate := ifaceVrrp.validate()
-	if validate != "" {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintln(w, validate)
+	validationErrors := ifaceVrrp.validate()
+	if len(validationErrors) != 0 {
+		writeValidationErrors(w, validationErrors)
 
 		return
 	}
+	mutex.Lock()
+	defer mutex.Unlock()
 	ifaceVrrpOldID = ifaceVrrp
 	ifaceVrrpOldID.IDVrrp = vars["old_Id_vrrp"]
-	if len(ifaceVrrp.IPVip) != 0 {
-		mutex.Lock()
-		vrrpExistsMaster := checkVrrpExists(ifaceVrrpOldID)
-		if vrrpExistsMaster {
-			vrrpOkMaster, err := checkVrrpWithoutSync(ifaceVrrpOldID)
-			if err != nil {
-				http.Error(w, err.Error(), 500)
-				mutex.Unlock()
-
-				return
-			}
-			if vrrpOkMaster {
-				vrrpExistsSlave, err := checkVrrpSlaveExists(ifaceVrrpOldID)
-				if err != nil {
-					http.Error(w, err.Error(), 500)
-					mutex.Unlock()
-
-					return
-				}
-				if vrrpExistsSlave {
-					vrrpOkSlave, err := checkVrrpSlaveWithoutSync(ifaceVrrpOldID)
-					if err != nil {
-						http.Error(w, err.Error(), 500)
-						mutex.Unlock()
-
-						return
-					}
-					if vrrpOkSlave {
-						err := removeVrrpSlave(ifaceVrrpOldID)
-						if err != nil {
-							http.Error(w, err.Error(), 500)
-							mutex.Unlock()
-
-							return
-						}
-						err = syncGroupAndReloadSlave()
-						if err != nil {
-							http.Error(w, err.Error(), 500)
-							mutex.Unlock()
-
-							return
-						}
-						sleep()
-
-						err = removeVrrp(ifaceVrrpOldID)
-						if err != nil {
-							http.Error(w, err.Error(), 500)
-							mutex.Unlock()
-
-							return
-						}
-						err = addVrrp(ifaceVrrp)
-						if err != nil {
-							http.Error(w, err.Error(), 500)
-							mutex.Unlock()
-
-							return
-						}
-						err = reloadVrrp()
-						if err != nil {
-							http.Error(w, err.Error(), 500)
-							mutex.Unlock()
-
-							return
-						}
-						// reload twice for vmac up before add IP (bug keepalived)
-						// reload twice for new vrrp comme up
-						sleep()
-						err = syncGroupAndReload()
-						if err != nil {
-							http.Error(w, err.Error(), 500)
-							mutex.Unlock()
-
-							return
-						}
-						sleep()
+	if len(ifaceVrrp.IPVip) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, "IP_vip empty, no move needed")
 
-						err = addVrrpSlave(ifaceVrrp)
-						if err != nil {
-							http.Error(w, err.Error(), 500)
-							mutex.Unlock()
+		return
+	}
+	if !checkVrrpExists(ifaceVrrpOldID) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, "unknown old vrrp id on master")
 
-							return
-						}
-						err = syncGroupAndReloadSlave()
-						if err != nil {
-							http.Error(w, err.Error(), 500)
-							mutex.Unlock()
+		return
+	}
+	vrrpOkMaster, err := checkVrrpWithoutSync(ifaceVrrpOldID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
 
-							return
-						}
-						// reload twice for vmac up before add IP (bug keepalived)
-						if ifaceVrrp.UseVmac {
-							sleep()
-							err = syncGroupAndReloadSlave()
-							if err != nil {
-								http.Error(w, err.Error(), 500)
-								mutex.Unlock()
-
-								return
-							}
-						}
-						sleep()
+		return
+	}
+	if !vrrpOkMaster {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, "different vrrp on master => you can't change Id_vrrp and others options at the same time")
 
-						mutex.Unlock()
-					} else {
-						mutex.Unlock()
-						w.WriteHeader(http.StatusBadRequest)
-						fmt.Fprintln(w, "different vrrp on slave => you can't change Id_vrrp and others options at the same time")
+		return
+	}
+	for _, peer := range peers {
+		vrrpExistsPeer, err := checkVrrpPeerExists(peer, ifaceVrrpOldID)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
 
-						return
-					}
-				} else {
-					mutex.Unlock()
-					w.WriteHeader(http.StatusBadRequest)
-					fmt.Fprintln(w, "unknown old vrrp id on slave")
+			return
+		}
+		if !vrrpExistsPeer {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, "unknown old vrrp id on slave", peer.Name)
 
-					return
-				}
-			} else {
-				mutex.Unlock()
-				w.WriteHeader(http.StatusBadRequest)
-				fmt.Fprintln(w, "different vrrp on master => you can't change Id_vrrp and others options at the same time")
+			return
+		}
+		vrrpOkPeer, err := checkVrrpPeerWithoutSync(peer, ifaceVrrpOldID)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
 
-				return
-			}
-		} else {
-			mutex.Unlock()
+			return
+		}
+		if !vrrpOkPeer {
 			w.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintln(w, "unknown old vrrp id on master")
+			fmt.Fprintln(w, "different vrrp on slave", peer.Name,
+				"=> you can't change Id_vrrp and others options at the same time")
 
 			return
 		}
-	} else {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintln(w, "IP_vip empty, no move needed")
+	}
+	plan := planType{dryRun: r.URL.Query().Get("dry_run") == "true"}
+	// remove old id on slave before change on master for no vrrp flap on slave
+	for _, peer := range peers {
+		err = plan.removeVrrpSteps(peerOps(peer), ifaceVrrpOldID)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+	}
+	err = plan.changeVrrpSteps(masterOps(), ifaceVrrp, ifaceVrrpOldID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
 
 		return
 	}
+	for _, peer := range peers {
+		err = plan.changeVrrpSteps(peerOps(peer), ifaceVrrp, ifaceVrrpType{})
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+	}
+	runPlan(w, r, &plan)
 }
 
 // add vrrp script file and reload keepalived on master and slave.
 func addVrrpScript(w http.ResponseWriter, r *http.Request) {
-	if *htpasswdfile != "" {
-		htpasswd := auth.HtpasswdFileProvider(*htpasswdfile)
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
 		authenticator := auth.BasicAuth{
 			Realm:   "Basic Realm",
 			Secrets: htpasswd,
@@ -1258,90 +986,66 @@ func addVrrpScript(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	validate := vrrpScript.validate()
-	if validate != "" {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintln(w, validate)
+	validationErrors := vrrpScript.validate()
+	if len(validationErrors) != 0 {
+		writeValidationErrors(w, validationErrors)
 
 		return
 	}
 	mutex.Lock()
+	defer mutex.Unlock()
 	if checkVrrpScriptExists(vrrpScript.Name) {
 		vrrpScriptOk, err := checkVrrpScriptOk(vrrpScript)
 		if err != nil {
-			mutex.Unlock()
 			http.Error(w, err.Error(), 500)
 
 			return
 		}
 		if !vrrpScriptOk {
-			mutex.Unlock()
 			w.WriteHeader(http.StatusBadRequest)
 			fmt.Fprintln(w, "vrrp_script already exist on master with different config")
-		}
-	} else {
-		err := addVrrpScriptFile(vrrpScript)
-		if err != nil {
-			mutex.Unlock()
-			http.Error(w, err.Error(), 500)
-
-			return
-		}
-		err = reloadVrrp()
-		if err != nil {
-			mutex.Unlock()
-			http.Error(w, err.Error(), 500)
 
 			return
 		}
-		sleep()
-	}
-	vrrpScriptSlaveExists, err := checkVrrpScriptExistsSlave(vrrpScript)
-	if err != nil {
-		mutex.Unlock()
-		http.Error(w, err.Error(), 500)
-
-		return
 	}
-	if vrrpScriptSlaveExists {
-		vrrpScriptOk, err := vrrpScriptOkSlave(vrrpScript)
+	for _, peer := range peers {
+		vrrpScriptExistsPeer, err := checkVrrpScriptPeerExists(peer, vrrpScript)
 		if err != nil {
-			mutex.Unlock()
 			http.Error(w, err.Error(), 500)
 
 			return
 		}
-		if !vrrpScriptOk {
-			mutex.Unlock()
-			w.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintln(w, "vrrp_script already exist on slave with different config")
+		if vrrpScriptExistsPeer {
+			vrrpScriptOk, err := checkVrrpScriptPeerOk(peer, vrrpScript)
+			if err != nil {
+				http.Error(w, err.Error(), 500)
 
-			return
-		}
-	} else {
-		err := addVrrpScriptSlave(vrrpScript)
-		if err != nil {
-			mutex.Unlock()
-			http.Error(w, err.Error(), 500)
+				return
+			}
+			if !vrrpScriptOk {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintln(w, "vrrp_script already exist on slave", peer.Name, "with different config")
 
-			return
+				return
+			}
 		}
-		err = reloadVrrpSlave()
+	}
+	plan := planType{dryRun: r.URL.Query().Get("dry_run") == "true"}
+	for _, node := range nodesOps() {
+		err = plan.addVrrpScriptSteps(node, vrrpScript)
 		if err != nil {
-			mutex.Unlock()
 			http.Error(w, err.Error(), 500)
 
 			return
 		}
-		sleep()
 	}
-	mutex.Unlock()
+	runPlan(w, r, &plan)
 }
 
 // remove vrrp script file and reload keepalived on master and slave.
 func removeVrrpScript(w http.ResponseWriter, r *http.Request) {
-	if *htpasswdfile != "" {
-		htpasswd := auth.HtpasswdFileProvider(*htpasswdfile)
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
 		authenticator := auth.BasicAuth{
 			Realm:   "Basic Realm",
 			Secrets: htpasswd,
@@ -1363,72 +1067,168 @@ func removeVrrpScript(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	mutex.Lock()
-	if checkVrrpScriptExists(vrrpScript.Name) {
-		err := removeVrrpScriptFile(vrrpScript)
+	defer mutex.Unlock()
+	plan := planType{dryRun: r.URL.Query().Get("dry_run") == "true"}
+	for _, node := range nodesOps() {
+		err = plan.removeVrrpScriptSteps(node, vrrpScript.Name)
 		if err != nil {
-			mutex.Unlock()
 			http.Error(w, err.Error(), 500)
 
 			return
 		}
-		err = reloadVrrp()
-		if err != nil {
-			mutex.Unlock()
-			http.Error(w, err.Error(), 500)
+	}
+	runPlan(w, r, &plan)
+}
 
-			return
-		}
-		sleep()
-	} else {
-		err = reloadVrrp()
-		if err != nil {
-			mutex.Unlock()
-			http.Error(w, err.Error(), 500)
+func changeVrrpScript(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.NewBasicAuthenticator("Basic Realm", htpasswd)
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
 
 			return
 		}
-		sleep()
 	}
-	vrrpScriptSlaveExists, err := checkVrrpScriptExistsSlave(vrrpScript)
-	if err != nil {
-		mutex.Unlock()
+	var vrrpScript vrrpScriptType
+	vars := mux.Vars(r)
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&vrrpScript)
+	if err != nil {
 		http.Error(w, err.Error(), 500)
 
 		return
 	}
-	if vrrpScriptSlaveExists {
-		err := removeVrrpScriptSlave(vrrpScript)
+	if vrrpScript.Name != vars["name"] {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, "name in url and json are not same")
+
+		return
+	}
+
+	validationErrors := vrrpScript.validate()
+	if len(validationErrors) != 0 {
+		writeValidationErrors(w, validationErrors)
+
+		return
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	plan := planType{dryRun: r.URL.Query().Get("dry_run") == "true"}
+	for _, node := range nodesOps() {
+		err = plan.changeVrrpScriptSteps(node, vrrpScript)
 		if err != nil {
-			mutex.Unlock()
 			http.Error(w, err.Error(), 500)
 
 			return
 		}
-		err = reloadVrrpSlave()
+	}
+	runPlan(w, r, &plan)
+}
+
+// read vrrp file on master and check if same on slave.
+func checkVrrpScript(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	vars := mux.Vars(r)
+	var vrrpScriptRead vrrpScriptType
+	var err error
+	if checkVrrpScriptExists(vars["name"]) {
+		vrrpScriptRead, err = readVrrpScriptFile(vars["name"])
 		if err != nil {
-			mutex.Unlock()
 			http.Error(w, err.Error(), 500)
 
 			return
 		}
-		sleep()
 	} else {
-		err = reloadVrrpSlave()
+		w.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+
+	vrrpScriptSlaveExists, err := checkVrrpScriptExistsSlave(vrrpScriptType{
+		Name: vars["name"],
+	})
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	if !vrrpScriptSlaveExists {
+		http.Error(w, "script exists on master but not find on slave", 500)
+	} else {
+		vrrpScriptOk, err := vrrpScriptOkSlave(vrrpScriptRead)
 		if err != nil {
-			mutex.Unlock()
 			http.Error(w, err.Error(), 500)
 
 			return
 		}
-		sleep()
+		if !vrrpScriptOk {
+			http.Error(w, "script master/slave not same", 500)
+
+			return
+		}
+	}
+	js, err := json.Marshal(vrrpScriptRead)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
 	}
-	mutex.Unlock()
 }
 
-func changeVrrpScript(w http.ResponseWriter, r *http.Request) {
-	if *htpasswdfile != "" {
-		htpasswd := auth.HtpasswdFileProvider(*htpasswdfile)
-		authenticator := auth.NewBasicAuthenticator("Basic Realm", htpasswd)
+// check vrrpScriptType parameters, all errors are returned.
+func (vrrpScript vrrpScriptType) validate() []validationErrorType {
+	var errors []validationErrorType
+	if vrrpScript.Interval < 1 {
+		errors = appendValidationError(errors, "interval", validationOutOfRange, "interval too small")
+	}
+	if vrrpScript.Timeout > vrrpScript.Interval {
+		errors = appendValidationError(errors, "timeout", validationOutOfRange, "timeout too long with this interval")
+	}
+	if vrrpScript.Weight < -253 || vrrpScript.Weight > 253 {
+		errors = appendValidationError(errors, "weight", validationOutOfRange, "weight is not in valid range")
+	}
+	if vrrpScript.Script == "" {
+		errors = appendValidationError(errors, "script", validationRequired, "missing script")
+	}
+	if vrrpScript.Fall < 1 {
+		errors = appendValidationError(errors, "fall", validationOutOfRange, "fall too small")
+	}
+	if vrrpScript.Rise < 1 {
+		errors = appendValidationError(errors, "rise", validationOutOfRange, "rise too small")
+	}
+
+	return errors
+}
+
+// add virtual server file and reload keepalived on master and slave.
+func addVirtualServer(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
 		usercheck := authenticator.CheckAuth(r)
 		if usercheck == "" {
 			w.WriteHeader(http.StatusUnauthorized)
@@ -1436,93 +1236,175 @@ func changeVrrpScript(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	var vrrpScript vrrpScriptType
+
+	var virtualServer virtualServerType
 	vars := mux.Vars(r)
 	dec := json.NewDecoder(r.Body)
-	err := dec.Decode(&vrrpScript)
+	err := dec.Decode(&virtualServer)
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 
 		return
 	}
-	if vrrpScript.Name != vars["name"] {
+
+	if virtualServer.Name != vars["name"] {
 		w.WriteHeader(http.StatusBadRequest)
 		fmt.Fprintln(w, "name in url and json are not same")
 
 		return
 	}
 
-	validate := vrrpScript.validate()
-	if validate != "" {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintln(w, validate)
+	validationErrors := virtualServer.validate()
+	if len(validationErrors) != 0 {
+		writeValidationErrors(w, validationErrors)
 
 		return
 	}
 	mutex.Lock()
-	if checkVrrpScriptExists(vrrpScript.Name) {
-		err = removeVrrpScriptFile(vrrpScript)
+	defer mutex.Unlock()
+	if checkVirtualServerExists(virtualServer.Name) {
+		virtualServerOk, err := checkVirtualServerOk(virtualServer)
 		if err != nil {
-			mutex.Unlock()
 			http.Error(w, err.Error(), 500)
 
+			return
+		}
+		if !virtualServerOk {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, "virtual_server already exist on master with different config")
+
 			return
 		}
 	}
-	err = addVrrpScriptFile(vrrpScript)
-	if err != nil {
-		mutex.Unlock()
-		http.Error(w, err.Error(), 500)
+	for _, peer := range peers {
+		virtualServerExistsPeer, err := checkVirtualServerPeerExists(peer, virtualServer)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
 
-		return
+			return
+		}
+		if virtualServerExistsPeer {
+			virtualServerOk, err := checkVirtualServerPeerOk(peer, virtualServer)
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+
+				return
+			}
+			if !virtualServerOk {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintln(w, "virtual_server already exist on slave", peer.Name, "with different config")
+
+				return
+			}
+		}
 	}
-	err = reloadVrrp()
-	if err != nil {
-		mutex.Unlock()
-		http.Error(w, err.Error(), 500)
+	plan := planType{dryRun: r.URL.Query().Get("dry_run") == "true"}
+	for _, node := range nodesOps() {
+		err = plan.addVirtualServerSteps(node, virtualServer)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
 
-		return
+			return
+		}
 	}
-	sleep()
+	runPlan(w, r, &plan)
+}
 
-	vrrpScriptSlaveExists, err := checkVrrpScriptExistsSlave(vrrpScript)
-	if err != nil {
-		mutex.Unlock()
-		http.Error(w, err.Error(), 500)
+// remove virtual server file and reload keepalived on master and slave.
+func removeVirtualServer(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+
+	vars := mux.Vars(r)
+	if !validVirtualServerName(vars["name"]) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, virtualServerNameMessage)
 
 		return
 	}
-	if vrrpScriptSlaveExists {
-		err = removeVrrpScriptSlave(vrrpScript)
+	virtualServer := virtualServerType{
+		Name: vars["name"],
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	plan := planType{dryRun: r.URL.Query().Get("dry_run") == "true"}
+	for _, node := range nodesOps() {
+		err := plan.removeVirtualServerSteps(node, virtualServer.Name)
 		if err != nil {
-			mutex.Unlock()
 			http.Error(w, err.Error(), 500)
 
 			return
 		}
 	}
-	err = addVrrpScriptSlave(vrrpScript)
+	runPlan(w, r, &plan)
+}
+
+// change virtual server file and reload keepalived on master and slave.
+func changeVirtualServer(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	var virtualServer virtualServerType
+	vars := mux.Vars(r)
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&virtualServer)
 	if err != nil {
-		mutex.Unlock()
 		http.Error(w, err.Error(), 500)
 
 		return
 	}
-	err = reloadVrrpSlave()
-	if err != nil {
-		mutex.Unlock()
-		http.Error(w, err.Error(), 500)
+	if virtualServer.Name != vars["name"] {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, "name in url and json are not same")
+
+		return
+	}
+
+	validationErrors := virtualServer.validate()
+	if len(validationErrors) != 0 {
+		writeValidationErrors(w, validationErrors)
 
 		return
 	}
-	sleep()
-	mutex.Unlock()
+	mutex.Lock()
+	defer mutex.Unlock()
+	plan := planType{dryRun: r.URL.Query().Get("dry_run") == "true"}
+	for _, node := range nodesOps() {
+		err = plan.changeVirtualServerSteps(node, virtualServer)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+	}
+	runPlan(w, r, &plan)
 }
 
-// read vrrp file on master and check if same on slave.
-func checkVrrpScript(w http.ResponseWriter, r *http.Request) {
-	if *htpasswdfile != "" {
-		htpasswd := auth.HtpasswdFileProvider(*htpasswdfile)
+// read virtual server file on master and check if same on slave.
+func checkVirtualServer(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
 		authenticator := auth.BasicAuth{
 			Realm:   "Basic Realm",
 			Secrets: htpasswd,
@@ -1535,10 +1417,16 @@ func checkVrrpScript(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	vars := mux.Vars(r)
-	var vrrpScriptRead vrrpScriptType
+	if !validVirtualServerName(vars["name"]) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, virtualServerNameMessage)
+
+		return
+	}
+	var virtualServerRead virtualServerType
 	var err error
-	if checkVrrpScriptExists(vars["name"]) {
-		vrrpScriptRead, err = readVrrpScriptFile(vars["name"])
+	if checkVirtualServerExists(vars["name"]) {
+		virtualServerRead, err = readVirtualServerFile(vars["name"])
 		if err != nil {
 			http.Error(w, err.Error(), 500)
 
@@ -1550,7 +1438,7 @@ func checkVrrpScript(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	vrrpScriptSlaveExists, err := checkVrrpScriptExistsSlave(vrrpScriptType{
+	virtualServerSlaveExists, err := checkVirtualServerExistsSlave(virtualServerType{
 		Name: vars["name"],
 	})
 	if err != nil {
@@ -1558,22 +1446,23 @@ func checkVrrpScript(w http.ResponseWriter, r *http.Request) {
 
 		return
 	}
-	if !vrrpScriptSlaveExists {
-		http.Error(w, "script exists on master but not find on slave", 500)
-	} else {
-		vrrpScriptOk, err := vrrpScriptOkSlave(vrrpScriptRead)
-		if err != nil {
-			http.Error(w, err.Error(), 500)
+	if !virtualServerSlaveExists {
+		http.Error(w, "virtual server exists on master but not find on slave", 500)
 
-			return
-		}
-		if !vrrpScriptOk {
-			http.Error(w, "script master/slave not same", 500)
+		return
+	}
+	virtualServerOk, err := virtualServerOkSlave(virtualServerRead)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
 
-			return
-		}
+		return
 	}
-	js, err := json.Marshal(vrrpScriptRead)
+	if !virtualServerOk {
+		http.Error(w, "virtual server master/slave not same", 500)
+
+		return
+	}
+	js, err := json.Marshal(virtualServerRead)
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 
@@ -1588,26 +1477,1000 @@ func checkVrrpScript(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// check vrrpScriptType parameters.
-func (vrrpScript vrrpScriptType) validate() string {
-	if vrrpScript.Interval < 1 {
-		return "interval too small"
+// validVirtualServerName : name of virtual_server is used in file name, only letters, digits, '_', '.' and '-'.
+func validVirtualServerName(name string) bool {
+	return virtualServerNameRegexp.MatchString(name)
+}
+
+// check virtualServerType parameters, all errors are returned.
+func (virtualServer virtualServerType) validate() []validationErrorType {
+	var errors []validationErrorType
+	if !validVirtualServerName(virtualServer.Name) {
+		errors = appendValidationError(errors, "name", validationInvalid, virtualServerNameMessage)
 	}
-	if vrrpScript.Timeout > vrrpScript.Interval {
-		return "timeout too long with this interval"
+	if net.ParseIP(virtualServer.VIP) == nil {
+		errors = appendValidationError(errors, "vip", validationInvalid, "vip is not a valid IP")
 	}
-	if vrrpScript.Weight < -253 || vrrpScript.Weight > 253 {
-		return "weight is not in valid range"
+	if virtualServer.Port < 1 || virtualServer.Port > 65535 {
+		errors = appendValidationError(errors, "port", validationOutOfRange, "port is not in valid range")
 	}
-	if vrrpScript.Script == "" {
-		return "missing script"
+	switch virtualServer.Protocol {
+	case "", "TCP", "UDP", "SCTP":
+	default:
+		errors = appendValidationError(errors, "protocol", validationInvalid, "protocol must be TCP, UDP or SCTP")
 	}
-	if vrrpScript.Fall < 1 {
-		return "fall too small"
+	switch virtualServer.LbAlgo {
+	case "", "rr", "wrr", "lc", "wlc", "lblc", "lblcr", "dh", "sh", "sed", "nq", "fo", "ovf", "mh":
+	default:
+		errors = appendValidationError(errors, "lb_algo", validationInvalid, "unknown lb_algo")
 	}
-	if vrrpScript.Rise < 1 {
-		return "fall too small"
+	switch virtualServer.LbKind {
+	case "", "NAT", "DR", "TUN":
+	default:
+		errors = appendValidationError(errors, "lb_kind", validationInvalid, "lb_kind must be NAT, DR or TUN")
+	}
+	if virtualServer.PersistenceTimeout < 0 {
+		errors = appendValidationError(errors, "persistence_timeout", validationOutOfRange,
+			"persistence_timeout too small")
+	}
+	realServers := make(map[string]bool)
+	for i, realServer := range virtualServer.RealServers {
+		field := fmt.Sprintf("real_server[%d]", i)
+		errors = append(errors, prefixValidationErrors(field, realServer.validate())...)
+		realServerKey := net.JoinHostPort(realServer.IP, strconv.Itoa(realServer.Port))
+		if realServers[realServerKey] {
+			errors = appendValidationError(errors, field, validationDuplicate,
+				strings.Join([]string{"real_server defined twice : ", realServerKey}, ""))
+		}
+		realServers[realServerKey] = true
+	}
+
+	return errors
+}
+
+// check realServerType parameters, all errors are returned.
+func (realServer realServerType) validate() []validationErrorType {
+	var errors []validationErrorType
+	if net.ParseIP(realServer.IP) == nil {
+		errors = appendValidationError(errors, "ip", validationInvalid,
+			strings.Join([]string{"real_server ip is not a valid IP : ", realServer.IP}, ""))
+	}
+	if realServer.Port < 1 || realServer.Port > 65535 {
+		errors = appendValidationError(errors, "port", validationOutOfRange,
+			strings.Join([]string{"real_server port is not in valid range for ", realServer.IP}, ""))
+	}
+	if realServer.Weight < 0 || realServer.Weight > 65535 {
+		errors = appendValidationError(errors, "weight", validationOutOfRange,
+			strings.Join([]string{"real_server weight is not in valid range for ", realServer.IP}, ""))
+	}
+	for _, checkerError := range prefixValidationErrors("checker", realServer.Checker.validate()) {
+		checkerError.Message = strings.Join([]string{checkerError.Message, " for real_server ", realServer.IP}, "")
+		errors = append(errors, checkerError)
+	}
+
+	return errors
+}
+
+// check checkerType parameters, all errors are returned.
+func (checker checkerType) validate() []validationErrorType {
+	var errors []validationErrorType
+	switch checker.Type {
+	case "":
+		return nil
+	case "TCP_CHECK":
+	case "HTTP_GET", "SSL_GET":
+		if checker.Path == "" {
+			errors = appendValidationError(errors, "path", validationRequired, "missing checker path")
+		} else if strings.ContainsAny(checker.Path, checkerForbiddenChars) {
+			errors = appendValidationError(errors, "path", validationInvalid,
+				"checker path must not contain space, quote, brace or newline")
+		}
+		if strings.ContainsAny(checker.Digest, checkerForbiddenChars) {
+			errors = appendValidationError(errors, "digest", validationInvalid,
+				"checker digest must not contain space, quote, brace or newline")
+		}
+		if checker.StatusCode < 0 || checker.StatusCode > 999 {
+			errors = appendValidationError(errors, "status_code", validationOutOfRange,
+				"checker status_code is not in valid range")
+		}
+	case "MISC_CHECK":
+		if checker.MiscPath == "" {
+			errors = appendValidationError(errors, "misc_path", validationRequired, "missing checker misc_path")
+		} else if strings.ContainsAny(checker.MiscPath, "\"{}\n\r") {
+			errors = appendValidationError(errors, "misc_path", validationInvalid,
+				"checker misc_path must not contain quote, brace or newline")
+		}
+		if checker.MiscTimeout < 0 {
+			errors = appendValidationError(errors, "misc_timeout", validationOutOfRange, "checker misc_timeout too small")
+		}
+	default:
+		errors = appendValidationError(errors, "type", validationInvalid,
+			"checker type must be TCP_CHECK, HTTP_GET, SSL_GET or MISC_CHECK")
+	}
+	if checker.ConnectPort < 0 || checker.ConnectPort > 65535 {
+		errors = appendValidationError(errors, "connect_port", validationOutOfRange,
+			"checker connect_port is not in valid range")
+	}
+	if checker.ConnectTimeout < 0 {
+		errors = appendValidationError(errors, "connect_timeout", validationOutOfRange,
+			"checker connect_timeout too small")
+	}
+	if checker.Retry < 0 {
+		errors = appendValidationError(errors, "retry", validationOutOfRange, "checker retry too small")
+	}
+	if checker.DelayBeforeRetry < 0 {
+		errors = appendValidationError(errors, "delay_before_retry", validationOutOfRange,
+			"checker delay_before_retry too small")
+	}
+
+	return errors
+}
+
+// addRealServer : on master API for add (or update) one real server of a virtual server on master & slave server.
+func addRealServer(w http.ResponseWriter, r *http.Request) {
+	changeRealServer(w, r, realServerAdd)
+}
+
+// removeRealServer : on master API for remove one real server of a virtual server on master & slave server.
+func removeRealServer(w http.ResponseWriter, r *http.Request) {
+	changeRealServer(w, r, realServerRemove)
+}
+
+// drainRealServer : on master API for set weight 0 on one real server of a virtual server on master & slave server.
+func drainRealServer(w http.ResponseWriter, r *http.Request) {
+	changeRealServer(w, r, realServerDrain)
+}
+
+func changeRealServer(w http.ResponseWriter, r *http.Request, action string) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	var realServer realServerType
+	vars := mux.Vars(r)
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&realServer)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
 	}
 
-	return ""
+	if !validVirtualServerName(vars["name"]) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, virtualServerNameMessage)
+
+		return
+	}
+
+	validationErrors := realServer.validate()
+	if len(validationErrors) != 0 {
+		writeValidationErrors(w, validationErrors)
+
+		return
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if !checkVirtualServerExists(vars["name"]) {
+		w.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+	if action != realServerAdd {
+		virtualServer, err := readVirtualServerFile(vars["name"])
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+		if realServerIndex(virtualServer, realServer) == -1 {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, "real_server", realServer.IP, realServer.Port, "not found in virtual_server", vars["name"])
+
+			return
+		}
+	}
+	plan := planType{dryRun: r.URL.Query().Get("dry_run") == "true"}
+	for _, node := range nodesOps() {
+		err = plan.changeRealServerSteps(node, vars["name"], realServer, action)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+	}
+	runPlan(w, r, &plan)
+}
+
+// listIfaceVrrp : on master API for list network and vrrp configuration on master & slave server.
+func listIfaceVrrp(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	inventory, err := readInventoryMasterSlave()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	js, err := json.Marshal(inventoryType{
+		Ifaces: inventory.Ifaces,
+		Vrrps:  inventory.Vrrps,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// listVrrpScript : on master API for list vrrp script on master & slave server.
+func listVrrpScript(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	inventory, err := readInventoryMasterSlave()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	js, err := json.Marshal(inventoryType{
+		VrrpScripts: inventory.VrrpScripts,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// listVirtualServer : on master API for list virtual server on master & slave server.
+func listVirtualServer(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	inventory, err := readInventoryMasterSlave()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	js, err := json.Marshal(inventoryType{
+		VirtualServers: inventory.VirtualServers,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// stateVrrp : on master API for read runtime state (MASTER, BACKUP, FAULT, ...) in keepalived and configured priority
+// of each vrrp instance on master & slave server, without body.
+func stateVrrp(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	vrrpStates, err := readVrrpStatesMasterSlave()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	js, err := json.Marshal(vrrpStates)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// readVrrpStatesMasterSlave : read state and priority of vrrp instances on master and slave and merge them
+// (with nodes in maintenance).
+func readVrrpStatesMasterSlave() ([]vrrpStateType, error) {
+	vrrpStatesMaster, err := readVrrpStates()
+	if err != nil {
+		return nil, err
+	}
+	vrrpStates := mergeVrrpStates(nil, "master", vrrpStatesMaster)
+	for _, peer := range peers {
+		vrrpStatesPeer, err := readVrrpStatesPeer(peer)
+		if err != nil {
+			return nil, err
+		}
+		vrrpStates = mergeVrrpStates(vrrpStates, peer.Name, vrrpStatesPeer)
+	}
+	nodesMaintenance, err := nodesInMaintenance()
+	if err != nil {
+		return nil, err
+	}
+	for _, vrrpState := range vrrpStates {
+		for node, nodeState := range vrrpState.Nodes {
+			if stringInSlice(node, nodesMaintenance) {
+				nodeState.Maintenance = true
+				vrrpState.Nodes[node] = nodeState
+			}
+		}
+	}
+
+	return vrrpStates, nil
+}
+
+// mergeVrrpStates : add state and priority read on node in 'nodes' of each vrrp instance
+// (sorted by Vrrp_group, iface, Id_vrrp).
+func mergeVrrpStates(vrrpStates []vrrpStateType, node string, vrrpStatesNode []vrrpStateType) []vrrpStateType {
+	for _, vrrpStateNode := range vrrpStatesNode {
+		nodeState := vrrpNodeStateType{
+			State: vrrpStateNode.State,
+			Prio:  vrrpStateNode.Prio,
+			Error: vrrpStateNode.Error,
+		}
+		found := false
+		for i := range vrrpStates {
+			if vrrpStates[i].VrrpGroup == vrrpStateNode.VrrpGroup &&
+				vrrpStates[i].Iface == vrrpStateNode.Iface &&
+				vrrpStates[i].IDVrrp == vrrpStateNode.IDVrrp {
+				vrrpStates[i].Nodes[node] = nodeState
+				found = true
+
+				break
+			}
+		}
+		if !found {
+			vrrpStates = append(vrrpStates, vrrpStateType{
+				Iface:     vrrpStateNode.Iface,
+				VrrpGroup: vrrpStateNode.VrrpGroup,
+				IDVrrp:    vrrpStateNode.IDVrrp,
+				Instance:  vrrpStateNode.Instance,
+				Nodes:     map[string]vrrpNodeStateType{node: nodeState},
+			})
+		}
+	}
+	sort.Slice(vrrpStates, func(i, j int) bool {
+		if vrrpStates[i].VrrpGroup != vrrpStates[j].VrrpGroup {
+			return vrrpStates[i].VrrpGroup < vrrpStates[j].VrrpGroup
+		}
+		if vrrpStates[i].Iface != vrrpStates[j].Iface {
+			return vrrpStates[i].Iface < vrrpStates[j].Iface
+		}
+
+		return vrrpStates[i].IDVrrp < vrrpStates[j].IDVrrp
+	})
+
+	return vrrpStates
+}
+
+// readInventoryMasterSlave : read inventory on master and slave and merge them.
+func readInventoryMasterSlave() (inventoryType, error) {
+	inventoryMaster, err := readInventory()
+	if err != nil {
+		return inventoryType{}, err
+	}
+	var inventory inventoryType
+	for i, peer := range peers {
+		inventorySlave, err := readInventoryPeer(peer)
+		if err != nil {
+			return inventoryType{}, err
+		}
+		inventoryPeer := inventoryType{
+			// network config is different between master and slave (IP, LACP), only presence is compared
+			Ifaces: mergeInventoryItems(inventoryMaster.Ifaces, inventorySlave.Ifaces, false),
+			// vrrp config is different between master and slave (priority), only presence and Vrrp_group are compared
+			Vrrps:          mergeInventoryItems(inventoryMaster.Vrrps, inventorySlave.Vrrps, false),
+			VrrpScripts:    mergeInventoryItems(inventoryMaster.VrrpScripts, inventorySlave.VrrpScripts, true),
+			VirtualServers: mergeInventoryItems(inventoryMaster.VirtualServers, inventorySlave.VirtualServers, true),
+		}
+		if i == 0 {
+			inventory = inventoryPeer
+
+			continue
+		}
+		inventory = inventoryType{
+			Ifaces:         intersectInventoryItems(inventory.Ifaces, inventoryPeer.Ifaces),
+			Vrrps:          intersectInventoryItems(inventory.Vrrps, inventoryPeer.Vrrps),
+			VrrpScripts:    intersectInventoryItems(inventory.VrrpScripts, inventoryPeer.VrrpScripts),
+			VirtualServers: intersectInventoryItems(inventory.VirtualServers, inventoryPeer.VirtualServers),
+		}
+	}
+
+	return inventory, nil
+}
+
+// intersectInventoryItems : merge items already merged with two peers,
+// slave and consistent flags only if true for both peers.
+func intersectInventoryItems(itemsPeerA, itemsPeerB []inventoryItemType) []inventoryItemType {
+	items := make(map[string]inventoryItemType)
+	keys := make([]string, 0)
+	for _, item := range itemsPeerA {
+		key := strings.Join([]string{item.Name, item.IDVrrp}, "_")
+		items[key] = item
+		keys = append(keys, key)
+	}
+	foundB := make(map[string]bool)
+	for _, item := range itemsPeerB {
+		key := strings.Join([]string{item.Name, item.IDVrrp}, "_")
+		foundB[key] = true
+		itemA, ok := items[key]
+		if !ok {
+			item.Slave = false
+			item.Consistent = false
+			items[key] = item
+			keys = append(keys, key)
+
+			continue
+		}
+		itemA.Master = itemA.Master || item.Master
+		itemA.Slave = itemA.Slave && item.Slave
+		itemA.Consistent = itemA.Consistent && item.Consistent
+		items[key] = itemA
+	}
+	sort.Strings(keys)
+	itemsMerged := make([]inventoryItemType, 0, len(keys))
+	for _, key := range keys {
+		item := items[key]
+		if !foundB[key] {
+			item.Slave = false
+			item.Consistent = false
+		}
+		itemsMerged = append(itemsMerged, item)
+	}
+
+	return itemsMerged
+}
+
+// mergeInventoryItems : merge items of master and slave with master/slave/consistent flags.
+func mergeInventoryItems(itemsMaster, itemsSlave []inventoryItemType, compareHash bool) []inventoryItemType {
+	items := make(map[string]inventoryItemType)
+	keys := make([]string, 0)
+	for _, item := range itemsMaster {
+		key := strings.Join([]string{item.Name, item.IDVrrp}, "_")
+		item.Master = true
+		items[key] = item
+		keys = append(keys, key)
+	}
+	for _, item := range itemsSlave {
+		key := strings.Join([]string{item.Name, item.IDVrrp}, "_")
+		itemMaster, ok := items[key]
+		if !ok {
+			item.Slave = true
+			items[key] = item
+			keys = append(keys, key)
+
+			continue
+		}
+		itemMaster.Slave = true
+		itemMaster.Consistent = itemMaster.VrrpGroup == item.VrrpGroup
+		if compareHash && itemMaster.Hash != item.Hash {
+			itemMaster.Consistent = false
+		}
+		items[key] = itemMaster
+	}
+	sort.Strings(keys)
+	itemsMerged := make([]inventoryItemType, 0, len(keys))
+	for _, key := range keys {
+		itemsMerged = append(itemsMerged, items[key])
+	}
+
+	return itemsMerged
+}
+
+// fillVrrpRead : copy vrrp parameters read on a server (except priority) in ifaceVrrp response.
+func fillVrrpRead(ifaceVrrpResponse *ifaceVrrpType, vrrpRead ifaceVrrpType) {
+	ifaceVrrpResponse.UseVmac = vrrpRead.UseVmac
+	ifaceVrrpResponse.VrrpGroup = vrrpRead.VrrpGroup
+	ifaceVrrpResponse.IfaceForVrrp = vrrpRead.IfaceForVrrp
+	ifaceVrrpResponse.IDVrrp = vrrpRead.IDVrrp
+	ifaceVrrpResponse.AuthType = vrrpRead.AuthType
+	ifaceVrrpResponse.AuthPass = vrrpRead.AuthPass
+	ifaceVrrpResponse.SyncIface = vrrpRead.SyncIface
+	ifaceVrrpResponse.GarpMDelay = vrrpRead.GarpMDelay
+	ifaceVrrpResponse.GarpMasterRefresh = vrrpRead.GarpMasterRefresh
+	ifaceVrrpResponse.AdvertInt = vrrpRead.AdvertInt
+	ifaceVrrpResponse.IPVip = vrrpRead.IPVip
+	ifaceVrrpResponse.TrackScript = vrrpRead.TrackScript
+}
+
+// fillPeerIP : set IP read on peer in IP_slave with only one peer, in IP_nodes otherwise.
+func fillPeerIP(ifaceVrrpResponse *ifaceVrrpType, peer peerType, ip string) {
+	if len(peers) == 1 {
+		ifaceVrrpResponse.IPSlave = ip
+
+		return
+	}
+	if ifaceVrrpResponse.IPNodes == nil {
+		ifaceVrrpResponse.IPNodes = make(map[string]string)
+	}
+	ifaceVrrpResponse.IPNodes[peer.Name] = ip
+}
+
+// fillPeerPrio : set priority read on peer in Prio_slave with only one peer, in Prio_nodes otherwise.
+func fillPeerPrio(ifaceVrrpResponse *ifaceVrrpType, peer peerType, prio string) {
+	if len(peers) == 1 {
+		ifaceVrrpResponse.PrioSlave = prio
+
+		return
+	}
+	if ifaceVrrpResponse.PrioNodes == nil {
+		ifaceVrrpResponse.PrioNodes = make(map[string]string)
+	}
+	ifaceVrrpResponse.PrioNodes[peer.Name] = prio
+}
+
+// importIfaceVrrp : on master API for read configuration (network + vrrp) on master & slave server without json.
+func importIfaceVrrp(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	vars := mux.Vars(r)
+	ifaceVrrpRead, existsMaster, err := readIfaceVrrp(vars["iface"])
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	partial := !existsMaster
+	existsOne := existsMaster
+	for _, peer := range peers {
+		ifaceVrrpReadSlave, existsSlave, err := readIfaceVrrpPeer(peer, vars["iface"])
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+		switch {
+		case !existsSlave:
+			partial = true
+
+			continue
+		case !existsOne:
+			ifaceVrrpRead = ifaceVrrpReadSlave
+		default:
+			if ifaceVrrpRead.IDVrrp != ifaceVrrpReadSlave.IDVrrp ||
+				ifaceVrrpRead.VrrpGroup != ifaceVrrpReadSlave.VrrpGroup ||
+				ifaceVrrpRead.IPVipOnly != ifaceVrrpReadSlave.IPVipOnly {
+				partial = true
+			}
+		}
+		existsOne = true
+		fillPeerIP(&ifaceVrrpRead, peer, ifaceVrrpReadSlave.IPSlave)
+		fillPeerPrio(&ifaceVrrpRead, peer, ifaceVrrpReadSlave.PrioSlave)
+		ifaceVrrpRead.LACPSlavesSlave = ifaceVrrpReadSlave.LACPSlavesSlave
+	}
+	if !existsOne {
+		w.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+	if partial {
+		w.WriteHeader(http.StatusPartialContent)
+	}
+	js, err := json.Marshal(ifaceVrrpRead)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// diffIfaceVrrp : on master API for diff between json and network + vrrp config files on master & slave server.
+func diffIfaceVrrp(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	var ifaceVrrp ifaceVrrpType
+	vars := mux.Vars(r)
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&ifaceVrrp)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+
+	ifaceVrrp.Iface = vars["iface"]
+	sort.Strings(ifaceVrrp.IPVip)
+	sort.Strings(ifaceVrrp.PostUp)
+	if ifaceVrrp.UseVmac {
+		if semver.Compare(keepalivedVersion, "v2.0.0") == 1 &&
+			semver.Compare(keepalivedVersion, "v2.0.13") == -1 {
+			ifaceVrrp.UseVmac = false
+		}
+	}
+
+	validationErrors := ifaceVrrp.validate()
+	if len(validationErrors) != 0 {
+		writeValidationErrors(w, validationErrors)
+
+		return
+	}
+	var diffReport diffReportType
+	diffReport.Master, err = diffIfaceVrrpFiles(ifaceVrrp)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	err = diffPeers(&diffReport, strings.Join([]string{"/diff_iface_vrrp/", ifaceVrrp.Iface, "/"}, ""), ifaceVrrp)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	js, err := json.Marshal(diffReport)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// diffVrrpScript : on master API for diff between json and vrrp script file on master & slave server.
+func diffVrrpScript(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	var vrrpScript vrrpScriptType
+	vars := mux.Vars(r)
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&vrrpScript)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	if vrrpScript.Name != vars["name"] {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, "name in url and json are not same")
+
+		return
+	}
+	var diffReport diffReportType
+	diffReport.Master, err = diffVrrpScriptFile(vrrpScript)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	err = diffPeers(&diffReport, strings.Join([]string{"/diff_vrrp_script/", vrrpScript.Name, "/"}, ""), vrrpScript)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	js, err := json.Marshal(diffReport)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// diffVirtualServer : on master API for diff between json and virtual server file on master & slave server.
+func diffVirtualServer(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	var virtualServer virtualServerType
+	vars := mux.Vars(r)
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&virtualServer)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	if virtualServer.Name != vars["name"] {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, "name in url and json are not same")
+
+		return
+	}
+	if !validVirtualServerName(virtualServer.Name) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, virtualServerNameMessage)
+
+		return
+	}
+	var diffReport diffReportType
+	diffReport.Master, err = diffVirtualServerFile(virtualServer)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	err = diffPeers(&diffReport, strings.Join([]string{
+		"/diff_virtual_server/", virtualServer.Name, "/",
+	}, ""), virtualServer)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	js, err := json.Marshal(diffReport)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// snapshot : on master API for read all files in managed directories on master & slave server, without body.
+func snapshot(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	snapshotNodes := snapshotType{
+		Version: snapshotVersion,
+		Date:    time.Now().UTC().Format(time.RFC3339),
+		Nodes:   make(map[string][]managedFileType),
+	}
+	for _, node := range nodesOps() {
+		files, err := node.readFiles()
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+		snapshotNodes.Nodes[node.name] = files
+	}
+	js, err := json.Marshal(snapshotNodes)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// restore : on master API for write files of snapshot (in json) on master & slave server,
+// ifup new iface and reload keepalived.
+func restore(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	var snapshotNodes snapshotType
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&snapshotNodes)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	if snapshotNodes.Version != snapshotVersion {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, "unknown snapshot version", snapshotNodes.Version)
+
+		return
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	plan := planType{dryRun: r.URL.Query().Get("dry_run") == "true"}
+	for _, node := range nodesOps() {
+		files, ok := snapshotNodes.Nodes[node.name]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, "node", node.name, "not in snapshot")
+
+			return
+		}
+		for _, file := range files {
+			if err := checkManagedPath(file.Path); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintln(w, err.Error())
+
+				return
+			}
+		}
+		err = plan.restoreFilesSteps(node, files)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+	}
+	runPlan(w, r, &plan)
+}
+
+// runPlan : apply plan with rollback on error (with progress in job if request is async),
+// or only return report of plan if dry run. The caller holds mutex since checks and build of plan
+// (files saved for rollback must not change before the run).
+func runPlan(w http.ResponseWriter, r *http.Request, plan *planType) {
+	if plan.dryRun {
+		js, err := json.Marshal(plan.report())
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, err = w.Write(js)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+
+		return
+	}
+	if job := jobFromContext(r.Context()); job != nil {
+		plan.progress = jobStepDone(job)
+	}
+	err := plan.run()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
 }
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	auth "github.com/abbot/go-http-auth"
+	"github.com/gorilla/mux"
+)
+
+// metricsBuckets : upper bounds in seconds of histograms (requests wait keepalived and ifup up to -ready_timeout).
+var metricsBuckets = []float64{0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+type histogramType struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+type metricsType struct {
+	sync.Mutex
+	keepalivedReloads        uint64
+	keepalivedReloadFailures uint64
+	requests                 map[string]uint64
+	requestsDuration         map[string]*histogramType
+	slaveRequestsDuration    map[string]*histogramType
+	slaveRequestsErrors      map[string]uint64
+}
+
+var metrics = &metricsType{
+	requests:              make(map[string]uint64),
+	requestsDuration:      make(map[string]*histogramType),
+	slaveRequestsDuration: make(map[string]*histogramType),
+	slaveRequestsErrors:   make(map[string]uint64),
+}
+
+// observe : add a duration in histogram of labels.
+func observe(histograms map[string]*histogramType, labels string, duration time.Duration) {
+	histogram, ok := histograms[labels]
+	if !ok {
+		histogram = &histogramType{counts: make([]uint64, len(metricsBuckets))}
+		histograms[labels] = histogram
+	}
+	seconds := duration.Seconds()
+	for i, bucket := range metricsBuckets {
+		if seconds <= bucket {
+			histogram.counts[i]++
+		}
+	}
+	histogram.count++
+	histogram.sum += seconds
+}
+
+// metricsLabel : label for exposition format with value escaped.
+func metricsLabel(name, value string) string {
+	value = strings.ReplaceAll(value, "\\", "\\\\")
+	value = strings.ReplaceAll(value, "\"", "\\\"")
+	value = strings.ReplaceAll(value, "\n", "\\n")
+
+	return strings.Join([]string{name, "=\"", value, "\""}, "")
+}
+
+// handlerName : name of function registered for route of request (unknown without route).
+func handlerName(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil || route.GetHandler() == nil {
+		return "unknown"
+	}
+	handler := route.GetHandler()
+	if handlerFunc, ok := handler.(http.HandlerFunc); ok {
+		name := runtime.FuncForPC(reflect.ValueOf(handlerFunc).Pointer()).Name()
+
+		return name[strings.LastIndex(name, ".")+1:]
+	}
+
+	return reflect.TypeOf(handler).String()
+}
+
+// metricsResponseWriter : http.ResponseWriter for keep status code of response.
+type metricsResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (mw *metricsResponseWriter) WriteHeader(statusCode int) {
+	if mw.statusCode == 0 {
+		mw.statusCode = statusCode
+	}
+	mw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (mw *metricsResponseWriter) Write(b []byte) (int, error) {
+	if mw.statusCode == 0 {
+		mw.statusCode = http.StatusOK
+	}
+
+	return mw.ResponseWriter.Write(b)
+}
+
+// metricsMiddleware : count requests by handler and status code and observe their duration.
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		mw := &metricsResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(mw, r)
+		if mw.statusCode == 0 {
+			mw.statusCode = http.StatusOK
+		}
+		handler := metricsLabel("handler", handlerName(r))
+		metrics.Lock()
+		metrics.requests[strings.Join([]string{
+			handler, metricsLabel("code", strconv.Itoa(mw.statusCode))}, ",")]++
+		observe(metrics.requestsDuration, handler, time.Since(start))
+		metrics.Unlock()
+	})
+}
+
+// observeSlaveRequest : observe duration of a request to slave peer, count it in errors if it fails.
+func observeSlaveRequest(peer peerType, start time.Time, statuscode int, err error) {
+	label := metricsLabel("peer", peer.Name)
+	metrics.Lock()
+	defer metrics.Unlock()
+	observe(metrics.slaveRequestsDuration, label, time.Since(start))
+	if err != nil || statuscode >= http.StatusInternalServerError {
+		metrics.slaveRequestsErrors[label]++
+	}
+}
+
+// observeKeepalivedReload : count reload of keepalived and failures.
+func observeKeepalivedReload(err error) {
+	metrics.Lock()
+	defer metrics.Unlock()
+	metrics.keepalivedReloads++
+	if err != nil {
+		metrics.keepalivedReloadFailures++
+	}
+}
+
+// writeMetricsHelp : HELP and TYPE lines of a metric.
+func writeMetricsHelp(out *strings.Builder, name, metricType, help string) {
+	fmt.Fprintf(out, "# HELP %v %v\n# TYPE %v %v\n", name, help, name, metricType)
+}
+
+// writeMetricsCounters : lines of a counter sorted by labels.
+func writeMetricsCounters(out *strings.Builder, name string, counters map[string]uint64) {
+	labels := make([]string, 0, len(counters))
+	for label := range counters {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		fmt.Fprintf(out, "%v{%v} %v\n", name, label, counters[label])
+	}
+}
+
+// writeMetricsHistograms : lines of a histogram (buckets, sum and count) sorted by labels.
+func writeMetricsHistograms(out *strings.Builder, name string, histograms map[string]*histogramType) {
+	labels := make([]string, 0, len(histograms))
+	for label := range histograms {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		histogram := histograms[label]
+		for i, bucket := range metricsBuckets {
+			fmt.Fprintf(out, "%v_bucket{%v,le=\"%v\"} %v\n",
+				name, label, strconv.FormatFloat(bucket, 'g', -1, 64), histogram.counts[i])
+		}
+		fmt.Fprintf(out, "%v_bucket{%v,le=\"+Inf\"} %v\n", name, label, histogram.count)
+		fmt.Fprintf(out, "%v_sum{%v} %v\n", name, label, strconv.FormatFloat(histogram.sum, 'g', -1, 64))
+		fmt.Fprintf(out, "%v_count{%v} %v\n", name, label, histogram.count)
+	}
+}
+
+// generateMetrics : metrics in Prometheus text format, managed items and vrrp states are read on this server.
+func generateMetrics() (string, error) {
+	var out strings.Builder
+	metrics.Lock()
+	writeMetricsHelp(&out, "lvsnetwork_http_requests_total", "counter", "Number of requests by handler and status code.")
+	writeMetricsCounters(&out, "lvsnetwork_http_requests_total", metrics.requests)
+	writeMetricsHelp(&out, "lvsnetwork_http_request_duration_seconds", "histogram", "Duration of requests by handler.")
+	writeMetricsHistograms(&out, "lvsnetwork_http_request_duration_seconds", metrics.requestsDuration)
+	writeMetricsHelp(&out, "lvsnetwork_slave_request_duration_seconds", "histogram",
+		"Duration of requests from master to slave peer.")
+	writeMetricsHistograms(&out, "lvsnetwork_slave_request_duration_seconds", metrics.slaveRequestsDuration)
+	writeMetricsHelp(&out, "lvsnetwork_slave_request_errors_total", "counter",
+		"Number of requests from master to slave peer failed or with status code 5xx.")
+	writeMetricsCounters(&out, "lvsnetwork_slave_request_errors_total", metrics.slaveRequestsErrors)
+	writeMetricsHelp(&out, "lvsnetwork_keepalived_reloads_total", "counter", "Number of keepalived reloads.")
+	fmt.Fprintf(&out, "lvsnetwork_keepalived_reloads_total %v\n", metrics.keepalivedReloads)
+	writeMetricsHelp(&out, "lvsnetwork_keepalived_reload_failures_total", "counter", "Number of keepalived reloads failed.")
+	fmt.Fprintf(&out, "lvsnetwork_keepalived_reload_failures_total %v\n", metrics.keepalivedReloadFailures)
+	metrics.Unlock()
+
+	inventory, err := readInventory()
+	if err != nil {
+		return "", err
+	}
+	writeMetricsHelp(&out, "lvsnetwork_managed_items", "gauge", "Number of items managed on this server by kind.")
+	fmt.Fprintf(&out, "lvsnetwork_managed_items{kind=\"iface\"} %v\n", len(inventory.Ifaces))
+	fmt.Fprintf(&out, "lvsnetwork_managed_items{kind=\"vrrp\"} %v\n", len(inventory.Vrrps))
+	fmt.Fprintf(&out, "lvsnetwork_managed_items{kind=\"vrrp_script\"} %v\n", len(inventory.VrrpScripts))
+	fmt.Fprintf(&out, "lvsnetwork_managed_items{kind=\"virtual_server\"} %v\n", len(inventory.VirtualServers))
+
+	vrrpStates, err := readVrrpStates()
+	if err != nil {
+		return "", err
+	}
+	writeMetricsHelp(&out, "lvsnetwork_vrrp_state", "gauge", "State of vrrp instance in keepalived on this server.")
+	for _, vrrpState := range vrrpStates {
+		for _, state := range []string{vrrpStateMaster, vrrpStateBackup, "FAULT", vrrpStateInit,
+			vrrpStateNotLoaded, vrrpStateUnknown} {
+			value := 0
+			if vrrpState.State == state {
+				value = 1
+			}
+			fmt.Fprintf(&out, "lvsnetwork_vrrp_state{%v} %v\n", strings.Join([]string{
+				metricsLabel("iface", vrrpState.Iface),
+				metricsLabel("Vrrp_group", vrrpState.VrrpGroup),
+				metricsLabel("Id_vrrp", vrrpState.IDVrrp),
+				metricsLabel("instance", vrrpState.Instance),
+				metricsLabel("state", state),
+			}, ","), value)
+		}
+	}
+	writeMetricsHelp(&out, "lvsnetwork_vrrp_priority", "gauge", "Configured priority of vrrp instance on this server.")
+	for _, vrrpState := range vrrpStates {
+		prio, err := strconv.Atoi(vrrpState.Prio)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&out, "lvsnetwork_vrrp_priority{%v} %v\n", strings.Join([]string{
+			metricsLabel("iface", vrrpState.Iface),
+			metricsLabel("Vrrp_group", vrrpState.VrrpGroup),
+			metricsLabel("Id_vrrp", vrrpState.IDVrrp),
+			metricsLabel("instance", vrrpState.Instance),
+		}, ","), prio)
+	}
+
+	return out.String(), nil
+}
+
+// onslaveMetrics : request received on slave for metrics of slave => generateMetrics().
+func onslaveMetrics(w http.ResponseWriter, r *http.Request) {
+	metricsText, err := generateMetrics()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprint(w, metricsText)
+}
+
+// getMetrics : on master API for metrics of master in Prometheus text format, without body.
+func getMetrics(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	metricsText, err := generateMetrics()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprint(w, metricsText)
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	auth "github.com/abbot/go-http-auth"
+)
+
+const (
+	notifyArgs     = 4
+	webhookTimeout = 10 * time.Second
+)
+
+var notifyMutex = &sync.Mutex{}
+
+// notifyLogPath : history of vrrp transitions (JSON lines).
+func notifyLogPath() string {
+	return filepath.Join(*stateDir, "notify.log")
+}
+
+// localNodeName : -node_name or hostname for identify node in notifications.
+func localNodeName() string {
+	if *nodeName != "" {
+		return *nodeName
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+
+	return hostname
+}
+
+// sendNotification : call /notify/ on url with arguments of keepalived notify (GROUP|INSTANCE name state priority),
+// used with -send_notify in command for -notify_cmd.
+func sendNotification(url string, args []string) error {
+	if len(args) < notifyArgs-1 {
+		return fmt.Errorf("need arguments of keepalived notify : GROUP|INSTANCE name state [priority]")
+	}
+	notification := notifyType{
+		Type:  args[0],
+		Name:  args[1],
+		State: args[2],
+	}
+	if len(args) >= notifyArgs {
+		notification.Prio = args[3]
+	}
+	body := new(bytes.Buffer)
+	err := json.NewEncoder(body).Encode(notification)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/json; charset=utf-8")
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("notify on %v return status %v", url, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// localRequest : request from an IP of this server.
+func localRequest(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() {
+		return true
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if ok && ipnet.IP.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// recordNotification : append transition in history log.
+func recordNotification(notification notifyType) error {
+	notifyMutex.Lock()
+	defer notifyMutex.Unlock()
+	err := os.MkdirAll(*stateDir, os.FileMode(permissionFileCreated))
+	if err != nil {
+		return err
+	}
+	notifyLog, err := os.OpenFile(notifyLogPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer notifyLog.Close()
+
+	return json.NewEncoder(notifyLog).Encode(notification)
+}
+
+// readNotifications : read history log, only the 'limit' last transitions if limit > 0.
+func readNotifications(limit int) ([]notifyType, error) {
+	notifyMutex.Lock()
+	defer notifyMutex.Unlock()
+	notifications := make([]notifyType, 0)
+	notifyLog, err := os.Open(notifyLogPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return notifications, nil
+		}
+
+		return notifications, err
+	}
+	defer notifyLog.Close()
+	scanner := bufio.NewScanner(notifyLog)
+	for scanner.Scan() {
+		var notification notifyType
+		err := json.Unmarshal(scanner.Bytes(), &notification)
+		if err != nil {
+			log.Printf("notification ignored : %v", err)
+
+			continue
+		}
+		notifications = append(notifications, notification)
+	}
+	err = scanner.Err()
+	if err != nil {
+		return notifications, err
+	}
+	if limit > 0 && len(notifications) > limit {
+		notifications = notifications[len(notifications)-limit:]
+	}
+
+	return notifications, nil
+}
+
+// sendWebhooks : POST transition on each url of -webhooks.
+func sendWebhooks(notification notifyType) {
+	if configWebhooks() == "" {
+		return
+	}
+	notificationByte, err := json.Marshal(notification)
+	if err != nil {
+		log.Printf("webhook error : %v", err)
+
+		return
+	}
+	client := &http.Client{Timeout: webhookTimeout}
+	for _, url := range strings.Split(configWebhooks(), ",") {
+		url := strings.TrimSpace(url)
+		if url == "" {
+			continue
+		}
+		go func() {
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url,
+				bytes.NewReader(notificationByte))
+			if err != nil {
+				log.Printf("webhook %v error : %v", url, err)
+
+				return
+			}
+			req.Header.Add("Content-Type", "application/json; charset=utf-8")
+			resp, err := client.Do(req)
+			if err != nil {
+				log.Printf("webhook %v error : %v", url, err)
+
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= http.StatusBadRequest {
+				log.Printf("webhook %v return status %v", url, resp.StatusCode)
+			}
+		}()
+	}
+}
+
+// notify : request received from keepalived notify (-send_notify) on this server for record a vrrp transition
+// in history log and send it to webhooks.
+func notify(w http.ResponseWriter, r *http.Request) {
+	if !localRequest(r) {
+		w.WriteHeader(http.StatusForbidden)
+
+		return
+	}
+	var notification notifyType
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&notification)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	notification.Node = localNodeName()
+	notification.Date = time.Now().UTC().Format(time.RFC3339)
+	err = recordNotification(notification)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	sendWebhooks(notification)
+}
+
+// notifyLimit : value of ?limit= in request (0 without limit).
+func notifyLimit(r *http.Request) (int, error) {
+	if r.URL.Query().Get("limit") == "" {
+		return 0, nil
+	}
+
+	return strconv.Atoi(r.URL.Query().Get("limit"))
+}
+
+// onslaveNotifyHistory : request received on slave to read history of vrrp transitions => readNotifications().
+func onslaveNotifyHistory(w http.ResponseWriter, r *http.Request) {
+	limit, err := notifyLimit(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, "bad limit", err)
+
+		return
+	}
+	notifications, err := readNotifications(limit)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	js, err := json.Marshal(notifications)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// notifyHistory : on master API for read history of vrrp transitions on master & slave server sorted by date
+// (?limit= for the last transitions), without body.
+func notifyHistory(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+	}
+	limit, err := notifyLimit(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, "bad limit", err)
+
+		return
+	}
+	notifications, err := readNotifications(0)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	for _, peer := range peers {
+		notificationsPeer, err := readNotificationsPeer(peer)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+		notifications = append(notifications, notificationsPeer...)
+	}
+	sort.SliceStable(notifications, func(i, j int) bool {
+		return notifications[i].Date < notifications[j].Date
+	})
+	if limit > 0 && len(notifications) > limit {
+		notifications = notifications[len(notifications)-limit:]
+	}
+	js, err := json.Marshal(notifications)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	auth "github.com/abbot/go-http-auth"
+	"github.com/gorilla/mux"
+)
+
+// openapiBodies : json body of routes (by path template), routes not listed are without body.
+var openapiBodies = map[string]reflect.Type{
+	"/add_iface_vrrp/{iface}/":                            reflect.TypeOf(ifaceVrrpType{}),
+	"/remove_iface_vrrp/{iface}/":                         reflect.TypeOf(ifaceVrrpType{}),
+	"/check_iface_vrrp/{iface}/":                          reflect.TypeOf(ifaceVrrpType{}),
+	"/change_iface_vrrp/{iface}/":                         reflect.TypeOf(ifaceVrrpType{}),
+	"/moveid_iface_vrrp/{iface}/{old_Id_vrrp}/":           reflect.TypeOf(ifaceVrrpType{}),
+	"/diff_iface_vrrp/{iface}/":                           reflect.TypeOf(ifaceVrrpType{}),
+	"/add_vrrp_script/{name}/":                            reflect.TypeOf(vrrpScriptType{}),
+	"/remove_vrrp_script/{name}/":                         reflect.TypeOf(vrrpScriptType{}),
+	"/check_vrrp_script/{name}/":                          reflect.TypeOf(vrrpScriptType{}),
+	"/change_vrrp_script/{name}/":                         reflect.TypeOf(vrrpScriptType{}),
+	"/diff_vrrp_script/{name}/":                           reflect.TypeOf(vrrpScriptType{}),
+	"/add_virtual_server/{name}/":                         reflect.TypeOf(virtualServerType{}),
+	"/remove_virtual_server/{name}/":                      reflect.TypeOf(virtualServerType{}),
+	"/check_virtual_server/{name}/":                       reflect.TypeOf(virtualServerType{}),
+	"/change_virtual_server/{name}/":                      reflect.TypeOf(virtualServerType{}),
+	"/diff_virtual_server/{name}/":                        reflect.TypeOf(virtualServerType{}),
+	"/add_real_server/{name}/":                            reflect.TypeOf(realServerType{}),
+	"/remove_real_server/{name}/":                         reflect.TypeOf(realServerType{}),
+	"/drain_real_server/{name}/":                          reflect.TypeOf(realServerType{}),
+	"/failover/{vrrp_group}/":                             reflect.TypeOf(failoverType{}),
+	"/notify/":                                            reflect.TypeOf(notifyType{}),
+	"/restore/":                                           reflect.TypeOf(snapshotType{}),
+	"/v2/interfaces/{iface}":                              reflect.TypeOf(ifaceVrrpType{}),
+	"/v2/interfaces/{iface}/diff":                         reflect.TypeOf(ifaceVrrpType{}),
+	"/v2/vrrp-scripts/{name}":                             reflect.TypeOf(vrrpScriptType{}),
+	"/v2/vrrp-scripts/{name}/diff":                        reflect.TypeOf(vrrpScriptType{}),
+	"/v2/virtual-servers/{name}":                          reflect.TypeOf(virtualServerType{}),
+	"/v2/virtual-servers/{name}/diff":                     reflect.TypeOf(virtualServerType{}),
+	"/v2/virtual-servers/{name}/real-servers/{ip}/{port}": reflect.TypeOf(realServerType{}),
+}
+
+var openapiPathVars = regexp.MustCompile(`{([^}:]+)(:[^}]*)?}`)
+
+// openapiSchemaName : name of schema in components for a struct type (without suffix Type).
+func openapiSchemaName(structType reflect.Type) string {
+	return strings.TrimSuffix(structType.Name(), "Type")
+}
+
+// openapiSchema : schema of a type, structs are added in schemas and referenced.
+func openapiSchema(valueType reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	switch valueType.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": openapiSchema(valueType.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": openapiSchema(valueType.Elem(), schemas),
+		}
+	case reflect.Ptr:
+		return openapiSchema(valueType.Elem(), schemas)
+	case reflect.Struct:
+		name := openapiSchemaName(valueType)
+		if _, ok := schemas[name]; !ok {
+			// reserve name before fields for recursive types
+			schemas[name] = nil
+			properties := make(map[string]interface{})
+			for i := 0; i < valueType.NumField(); i++ {
+				field := valueType.Field(i)
+				jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+				if field.PkgPath != "" || jsonName == "-" {
+					continue
+				}
+				if jsonName == "" {
+					jsonName = field.Name
+				}
+				properties[jsonName] = openapiSchema(field.Type, schemas)
+			}
+			schemas[name] = map[string]interface{}{"type": "object", "properties": properties}
+		}
+
+		return map[string]interface{}{"$ref": strings.Join([]string{"#/components/schemas/", name}, "")}
+	}
+
+	return map[string]interface{}{}
+}
+
+// openapiOperation : operation of a route for a method with parameters of path and json body.
+func openapiOperation(pathTemplate, method string, schemas map[string]interface{}) map[string]interface{} {
+	operation := map[string]interface{}{
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{"description": "OK"},
+		},
+	}
+	parameters := make([]interface{}, 0)
+	for _, pathVar := range openapiPathVars.FindAllStringSubmatch(pathTemplate, -1) {
+		parameters = append(parameters, map[string]interface{}{
+			"name":     pathVar[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+	if len(parameters) != 0 {
+		operation["parameters"] = parameters
+	}
+	if strings.HasPrefix(pathTemplate, "/v2/") {
+		responses := operation["responses"].(map[string]interface{})
+		switch method {
+		case http.MethodPut:
+			responses["201"] = map[string]interface{}{"description": "Created"}
+			responses["204"] = map[string]interface{}{"description": "No Content"}
+		case http.MethodPatch, http.MethodDelete:
+			responses["204"] = map[string]interface{}{"description": "No Content"}
+		}
+		responses["default"] = map[string]interface{}{
+			"description": "error",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": openapiSchema(reflect.TypeOf(v2ErrorType{}), schemas),
+				},
+			},
+		}
+	}
+	body, ok := openapiBodies[pathTemplate]
+	if !ok || method == http.MethodGet || method == http.MethodDelete ||
+		strings.HasSuffix(pathTemplate, "/drain") {
+		return operation
+	}
+	operation["requestBody"] = map[string]interface{}{
+		"required": true,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": openapiSchema(body, schemas),
+			},
+		},
+	}
+	_, validated := reflect.New(body).Interface().(interface{ validate() []validationErrorType })
+	if validated && !strings.HasPrefix(pathTemplate, "/v2/") {
+		operation["responses"].(map[string]interface{})["400"] = map[string]interface{}{
+			"description": "invalid parameters",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": openapiSchema(reflect.TypeOf(validationErrorsType{}), schemas),
+				},
+			},
+		}
+	}
+
+	return operation
+}
+
+// generateOpenAPI : OpenAPI 3 document of routes registered in router, legacy routes without methods
+// are documented with POST if they need a json body and GET otherwise.
+func generateOpenAPI(router *mux.Router) (map[string]interface{}, error) {
+	schemas := make(map[string]interface{})
+	paths := make(map[string]interface{})
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		pathTemplate, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			methods = []string{http.MethodGet}
+			if _, ok := openapiBodies[pathTemplate]; ok {
+				methods = []string{http.MethodPost}
+			}
+		}
+		sort.Strings(methods)
+		pathItem, ok := paths[pathTemplate].(map[string]interface{})
+		if !ok {
+			pathItem = make(map[string]interface{})
+			paths[pathTemplate] = pathItem
+		}
+		for _, method := range methods {
+			pathItem[strings.ToLower(method)] = openapiOperation(pathTemplate, method, schemas)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	document := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "lvsnetwork-api",
+			"version": "1",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+		},
+	}
+	if configHtpasswd() != "" {
+		document["components"].(map[string]interface{})["securitySchemes"] = map[string]interface{}{
+			"basicAuth": map[string]interface{}{"type": "http", "scheme": "basic"},
+		}
+		document["security"] = []interface{}{map[string]interface{}{"basicAuth": []string{}}}
+	}
+
+	return document, nil
+}
+
+// getOpenAPI : on master API for OpenAPI document generated from routes and request types, without body.
+func getOpenAPI(router *mux.Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if configHtpasswd() != "" {
+			htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+			authenticator := auth.BasicAuth{
+				Realm:   "Basic Realm",
+				Secrets: htpasswd,
+			}
+			usercheck := authenticator.CheckAuth(r)
+			if usercheck == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+
+				return
+			}
+		}
+		document, err := generateOpenAPI(router)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+		js, err := json.Marshal(document)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, err = w.Write(js)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+
+			return
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	readyPollInterval   = 500 * time.Millisecond
+	keepalivedDumpWait  = 100 * time.Millisecond
+	keepalivedDumpTries = 30
+	vrrpStateMaster     = "MASTER"
+	vrrpStateBackup     = "BACKUP"
+	vrrpStateInit       = "INIT"
+	vrrpStateUnknown    = "UNKNOWN"
+	vrrpStateNotLoaded  = "NOT_LOADED"
+)
+
+var (
+	keepalivedDataMutex = &sync.Mutex{}
+	// sysClassNetDir : directory with operstate of each iface
+	sysClassNetDir = "/sys/class/net/"
+)
+
+// pollUntil : call check until it returns ready or -ready_timeout is reached,
+// on timeout the error is the reason returned by the last check.
+func pollUntil(what string, check func() (bool, string)) error {
+	deadline := time.Now().Add(time.Duration(configReadyTimeout()) * time.Second)
+	for {
+		ready, reason := check()
+		if ready {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%v not ready after %vs : %v", what, configReadyTimeout(), reason)
+		}
+		time.Sleep(readyPollInterval)
+	}
+}
+
+// ifaceUp : check operstate of iface (without alias) in /sys/class/net/,
+// 'unknown' is up for virtual iface with flag up.
+func ifaceUp(iface string) (bool, string) {
+	ifaceCut := strings.Split(iface, ":")[0]
+	operstateByte, err := ioutil.ReadFile(strings.Join([]string{sysClassNetDir, ifaceCut, "/operstate"}, ""))
+	if err != nil {
+		return false, strings.Join([]string{"iface", ifaceCut, "not found"}, " ")
+	}
+	operstate := strings.TrimSpace(string(operstateByte))
+	switch operstate {
+	case "up":
+		return true, ""
+	case "unknown":
+		netIface, err := net.InterfaceByName(ifaceCut)
+		if err == nil && netIface.Flags&net.FlagUp != 0 {
+			return true, ""
+		}
+	}
+
+	return false, strings.Join([]string{"operstate of", ifaceCut, "is", operstate}, " ")
+}
+
+// waitIfaceUp : wait operstate up of iface.
+func waitIfaceUp(iface string) error {
+	return pollUntil(strings.Join([]string{"iface", iface}, " "), func() (bool, string) {
+		return ifaceUp(iface)
+	})
+}
+
+// vipsPresent : check if each IP of list is configured on an iface of server.
+func vipsPresent(vips []string) (bool, string) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false, err.Error()
+	}
+	for _, vip := range vips {
+		ip := net.ParseIP(strings.Split(vip, "/")[0])
+		found := false
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if ok && ipnet.IP.Equal(ip) {
+				found = true
+
+				break
+			}
+		}
+		if !found {
+			return false, strings.Join([]string{"VIP", vip, "not present"}, " ")
+		}
+	}
+
+	return true, ""
+}
+
+// readKeepalivedStates : state of each vrrp instance, read in dump written by keepalived on SIGUSR1 (-keepalived_data).
+func readKeepalivedStates() (map[string]string, error) {
+	keepalivedDataMutex.Lock()
+	defer keepalivedDataMutex.Unlock()
+	pidByte, err := ioutil.ReadFile(configKeepalivedPid())
+	if err != nil {
+		return nil, fmt.Errorf("keepalived not running : %v", err) // nolint: errorlint
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(pidByte)))
+	if err != nil {
+		return nil, fmt.Errorf("bad pid in %v : %v", configKeepalivedPid(), err) // nolint: errorlint
+	}
+	var lastDump time.Time
+	info, err := os.Stat(configKeepalivedData())
+	if err == nil {
+		lastDump = info.ModTime()
+	}
+	err = syscall.Kill(pid, syscall.SIGUSR1)
+	if err != nil {
+		return nil, fmt.Errorf("keepalived not running : %v", err) // nolint: errorlint
+	}
+	dumped := false
+	for i := 0; i < keepalivedDumpTries; i++ {
+		time.Sleep(keepalivedDumpWait)
+		info, err := os.Stat(configKeepalivedData())
+		if err == nil && info.ModTime().After(lastDump) {
+			dumped = true
+
+			break
+		}
+	}
+	if !dumped {
+		return nil, fmt.Errorf("keepalived doesn't write %v after SIGUSR1", configKeepalivedData())
+	}
+	data, err := ioutil.ReadFile(configKeepalivedData())
+	if err != nil {
+		return nil, err
+	}
+
+	return parseKeepalivedStates(string(data)), nil
+}
+
+// parseKeepalivedStates : read 'VRRP Instance = name' and first 'State = state' after it in keepalived dump.
+func parseKeepalivedStates(data string) map[string]string {
+	states := make(map[string]string)
+	instance := ""
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), " = ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "VRRP Instance":
+			instance = strings.TrimSpace(fields[1])
+		case "State":
+			if _, ok := states[instance]; instance != "" && !ok {
+				states[instance] = strings.TrimSpace(fields[1])
+			}
+		}
+	}
+
+	return states
+}
+
+// vrrpReady : check if vrrp instance is loaded by keepalived in BACKUP or MASTER state
+// (with vmac iface created and VIP present on MASTER).
+func vrrpReady(ifaceVrrp ifaceVrrpType) (bool, string) {
+	name := vrrpInstanceName(ifaceVrrp)
+	states, err := readKeepalivedStates()
+	if err != nil {
+		return false, err.Error()
+	}
+	state, ok := states[name]
+	if !ok {
+		return false, strings.Join([]string{"vrrp instance", name, "not loaded by keepalived"}, " ")
+	}
+	if state != vrrpStateBackup && state != vrrpStateMaster {
+		return false, strings.Join([]string{"vrrp instance", name, "in state", state}, " ")
+	}
+	if vmac := vmacIfaceName(ifaceVrrp); vmac != "" {
+		_, err := net.InterfaceByName(vmac)
+		if err != nil {
+			return false, strings.Join([]string{"vmac", vmac, "not created"}, " ")
+		}
+	}
+	if state == vrrpStateMaster {
+		return vipsPresent(ifaceVrrp.IPVip)
+	}
+
+	return true, ""
+}
+
+// waitVrrpReady : wait vrrp instance in BACKUP or MASTER state after reload of keepalived.
+func waitVrrpReady(ifaceVrrp ifaceVrrpType) error {
+	return pollUntil(strings.Join([]string{"vrrp instance", vrrpInstanceName(ifaceVrrp)}, " "), func() (bool, string) {
+		return vrrpReady(ifaceVrrp)
+	})
+}
+
+// waitVrrpRemoved : wait vrrp instance unloaded by keepalived after reload.
+func waitVrrpRemoved(ifaceVrrp ifaceVrrpType) error {
+	name := vrrpInstanceName(ifaceVrrp)
+
+	return pollUntil(strings.Join([]string{"removal of vrrp instance", name}, " "), func() (bool, string) {
+		states, err := readKeepalivedStates()
+		if err != nil {
+			return false, err.Error()
+		}
+		if state, ok := states[name]; ok {
+			return false, strings.Join([]string{"vrrp instance", name, "still loaded in state", state}, " ")
+		}
+
+		return true, ""
+	})
+}
+
+// configuredVrrpInstances : names of vrrp instances in vrrp config files of each Vrrp_group directory.
+func configuredVrrpInstances() (map[string]bool, error) {
+	instances := make(map[string]bool)
+	files, err := filepath.Glob(strings.Join([]string{keepalivedConfDir(), "*/*.conf"}, ""))
+	if err != nil {
+		return instances, err
+	}
+	for _, file := range files {
+		vrrpFileByte, err := ioutil.ReadFile(file)
+		if err != nil {
+			return instances, fmt.Errorf("read file %v error", file)
+		}
+		vrrpFileWords := strings.Fields(string(vrrpFileByte))
+		if len(vrrpFileWords) > 1 && vrrpFileWords[0] == "vrrp_instance" {
+			instances[vrrpFileWords[1]] = true
+		}
+	}
+
+	return instances, nil
+}
+
+// waitKeepalivedReady : wait keepalived answers after reload with the vrrp instances of config files
+// (a dump of the configuration before reload has other instances) and no vrrp instance in INIT state.
+func waitKeepalivedReady() error {
+	return pollUntil("keepalived", func() (bool, string) {
+		instances, err := configuredVrrpInstances()
+		if err != nil {
+			return false, err.Error()
+		}
+		states, err := readKeepalivedStates()
+		if err != nil {
+			return false, err.Error()
+		}
+		var instancesNotLoaded, instancesRemoved, instancesInit []string
+		for name := range instances {
+			if _, ok := states[name]; !ok {
+				instancesNotLoaded = append(instancesNotLoaded, name)
+			}
+		}
+		for name, state := range states {
+			if !instances[name] {
+				instancesRemoved = append(instancesRemoved, name)
+			}
+			if state == vrrpStateInit {
+				instancesInit = append(instancesInit, name)
+			}
+		}
+		switch {
+		case len(instancesNotLoaded) != 0:
+			sort.Strings(instancesNotLoaded)
+
+			return false, strings.Join([]string{
+				"vrrp instances not loaded :", strings.Join(instancesNotLoaded, ", "),
+			}, " ")
+		case len(instancesRemoved) != 0:
+			sort.Strings(instancesRemoved)
+
+			return false, strings.Join([]string{
+				"vrrp instances removed still loaded :", strings.Join(instancesRemoved, ", "),
+			}, " ")
+		case len(instancesInit) != 0:
+			sort.Strings(instancesInit)
+
+			return false, strings.Join([]string{"vrrp instances in state INIT :", strings.Join(instancesInit, ", ")}, " ")
+		}
+
+		return true, ""
+	})
+}
+
+// readVrrpStates : runtime state in keepalived and configured priority of each vrrp config file on server,
+// state is UNKNOWN (with error) if keepalived doesn't answer and NOT_LOADED if instance isn't in keepalived.
+func readVrrpStates() ([]vrrpStateType, error) {
+	inventory, err := readInventory()
+	if err != nil {
+		return nil, err
+	}
+	states, errStates := readKeepalivedStates()
+	vrrpStates := make([]vrrpStateType, 0, len(inventory.Vrrps))
+	for _, vrrp := range inventory.Vrrps {
+		vrrpRead, err := readVrrpFile(ifaceVrrpType{
+			Iface:     vrrp.Name,
+			VrrpGroup: vrrp.VrrpGroup,
+			IDVrrp:    vrrp.IDVrrp,
+		})
+		if err != nil {
+			return nil, err
+		}
+		vrrpState := vrrpStateType{
+			Iface:     vrrp.Name,
+			VrrpGroup: vrrp.VrrpGroup,
+			IDVrrp:    vrrp.IDVrrp,
+			Instance:  vrrpInstanceName(vrrpRead),
+			Prio:      vrrpRead.PrioMaster,
+		}
+		if *isSlave {
+			vrrpState.Prio = vrrpRead.PrioSlave
+		}
+		switch state, ok := states[vrrpState.Instance]; {
+		case errStates != nil:
+			vrrpState.State = vrrpStateUnknown
+			vrrpState.Error = errStates.Error()
+		case ok:
+			vrrpState.State = state
+		default:
+			vrrpState.State = vrrpStateNotLoaded
+		}
+		vrrpStates = append(vrrpStates, vrrpState)
+	}
+
+	return vrrpStates, nil
+}
@@ -287,6 +287,50 @@ func onslaveReloadVrrp(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// onslaveWaitKeepalived : request received on slave to wait keepalived ready after reload => waitKeepalivedReady().
+func onslaveWaitKeepalived(w http.ResponseWriter, r *http.Request) {
+	err := waitKeepalivedReady()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+	}
+}
+
+// onslaveWaitVrrp : request received on slave to wait vrrp instance in BACKUP or MASTER state => waitVrrpReady().
+func onslaveWaitVrrp(w http.ResponseWriter, r *http.Request) {
+	var IfaceVrrp ifaceVrrpType
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&IfaceVrrp)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	vars := mux.Vars(r)
+	IfaceVrrp.Iface = vars["iface"]
+	err = waitVrrpReady(IfaceVrrp)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+	}
+}
+
+// onslaveWaitVrrpRemoved : request received on slave to wait vrrp instance unloaded => waitVrrpRemoved().
+func onslaveWaitVrrpRemoved(w http.ResponseWriter, r *http.Request) {
+	var IfaceVrrp ifaceVrrpType
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&IfaceVrrp)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	vars := mux.Vars(r)
+	IfaceVrrp.Iface = vars["iface"]
+	err = waitVrrpRemoved(IfaceVrrp)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+	}
+}
+
 // onslaveAddVrrp : request received on slave to add vrrp config file => addVrrp().
 func onslaveAddVrrp(w http.ResponseWriter, r *http.Request) {
 	var IfaceVrrp ifaceVrrpType
@@ -393,3 +437,389 @@ func onslaveRemoveVrrpScript(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), 500)
 	}
 }
+
+// onslaveCheckVirtualServerExists : request received on slave to checkVirtualServerExists().
+func onslaveCheckVirtualServerExists(w http.ResponseWriter, r *http.Request) {
+	var virtualServer virtualServerType
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&virtualServer)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	virtualServerExists := checkVirtualServerExists(virtualServer.Name)
+	if !virtualServerExists {
+		w.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+}
+
+// onslaveCheckVirtualServerOk : request received on slave to checkVirtualServerOk().
+func onslaveCheckVirtualServerOk(w http.ResponseWriter, r *http.Request) {
+	var virtualServer virtualServerType
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&virtualServer)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	virtualServerOk, err := checkVirtualServerOk(virtualServer)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	if !virtualServerOk {
+		w.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+}
+
+// onslaveAddVirtualServer : request received on slave to addVirtualServerFile().
+func onslaveAddVirtualServer(w http.ResponseWriter, r *http.Request) {
+	var virtualServer virtualServerType
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&virtualServer)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	vars := mux.Vars(r)
+	if !validVirtualServerName(vars["name"]) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, virtualServerNameMessage)
+
+		return
+	}
+	virtualServer.Name = vars["name"]
+	err = addVirtualServerFile(virtualServer)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+	}
+}
+
+// onslaveRemoveVirtualServer : request received on slave to removeVirtualServerFile().
+func onslaveRemoveVirtualServer(w http.ResponseWriter, r *http.Request) {
+	var virtualServer virtualServerType
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&virtualServer)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	vars := mux.Vars(r)
+	if !validVirtualServerName(vars["name"]) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, virtualServerNameMessage)
+
+		return
+	}
+	virtualServer.Name = vars["name"]
+	err = removeVirtualServerFile(virtualServer)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+	}
+}
+
+// onslaveList : request received on slave to list config files => readInventory().
+func onslaveList(w http.ResponseWriter, r *http.Request) {
+	inventory, err := readInventory()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	js, err := json.Marshal(inventory)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// onslaveStateVrrp : request received on slave to read state and priority of vrrp instances => readVrrpStates().
+func onslaveStateVrrp(w http.ResponseWriter, r *http.Request) {
+	vrrpStates, err := readVrrpStates()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	js, err := json.Marshal(vrrpStates)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// onslaveReadIfaceVrrp : request received on slave to read network and vrrp config files => readIfaceVrrp().
+func onslaveReadIfaceVrrp(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ifaceVrrpRead, exists, err := readIfaceVrrp(vars["iface"])
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+	js, err := json.Marshal(ifaceVrrpRead)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// onslaveDiffIfaceVrrp : request received on slave to diff network and vrrp config files => diffIfaceVrrpFiles().
+func onslaveDiffIfaceVrrp(w http.ResponseWriter, r *http.Request) {
+	var IfaceVrrp ifaceVrrpType
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&IfaceVrrp)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	vars := mux.Vars(r)
+	IfaceVrrp.Iface = vars["iface"]
+	diff, err := diffIfaceVrrpFiles(IfaceVrrp)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	writeDiff(w, diff)
+}
+
+// onslaveDiffVrrpScript : request received on slave to diff vrrp script file => diffVrrpScriptFile().
+func onslaveDiffVrrpScript(w http.ResponseWriter, r *http.Request) {
+	var vrrpScript vrrpScriptType
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&vrrpScript)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	diff, err := diffVrrpScriptFile(vrrpScript)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	writeDiff(w, diff)
+}
+
+// onslaveDiffVirtualServer : request received on slave to diff virtual server file => diffVirtualServerFile().
+func onslaveDiffVirtualServer(w http.ResponseWriter, r *http.Request) {
+	var virtualServer virtualServerType
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&virtualServer)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	diff, err := diffVirtualServerFile(virtualServer)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	writeDiff(w, diff)
+}
+
+func writeDiff(w http.ResponseWriter, diff diffType) {
+	js, err := json.Marshal(diff)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// onslaveGetFile : request received on slave to read a file in managed directory => readManagedFile().
+func onslaveGetFile(w http.ResponseWriter, r *http.Request) {
+	var file managedFileType
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&file)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	if err := checkManagedPath(file.Path); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+
+		return
+	}
+	file, err = readManagedFile(file.Path)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	js, err := json.Marshal(file)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// onslavePutFile : request received on slave to write (or remove) a file in managed directory => writeManagedFile().
+func onslavePutFile(w http.ResponseWriter, r *http.Request) {
+	var file managedFileType
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&file)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	if err := checkManagedPath(file.Path); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+
+		return
+	}
+	err = writeManagedFile(file)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// onslaveGetFiles : request received on slave to read all files in managed directories => readManagedFiles().
+func onslaveGetFiles(w http.ResponseWriter, r *http.Request) {
+	files, err := readManagedFiles()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	js, err := json.Marshal(files)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// onslaveIfupIface : request received on slave to ifup an iface with network config file already written => ifupIface().
+func onslaveIfupIface(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	err := ifupIface(vars["iface"])
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// onslaveIfdownIface : request received on slave to ifdown an iface and keep its network config file => ifdownIface().
+func onslaveIfdownIface(w http.ResponseWriter, r *http.Request) {
+	var IfaceVrrp ifaceVrrpType
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&IfaceVrrp)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	vars := mux.Vars(r)
+	IfaceVrrp.Iface = vars["iface"]
+	err = ifdownIface(IfaceVrrp)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
+
+// onslaveGenerateIfaceVrrp : request received on slave to generate network and vrrp config files without write them
+// => generateIfaceVrrpFiles().
+func onslaveGenerateIfaceVrrp(w http.ResponseWriter, r *http.Request) {
+	var IfaceVrrp ifaceVrrpType
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&IfaceVrrp)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	vars := mux.Vars(r)
+	IfaceVrrp.Iface = vars["iface"]
+	files, err := generateIfaceVrrpFiles(IfaceVrrp)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	js, err := json.Marshal(files)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+}
@@ -9,48 +9,38 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 )
 
-// requestSlave : call HTTP request from MASTER to SLAVE.
+// requestSlave : call HTTP request from MASTER to all SLAVE peers,
+// return the first response not OK or the response of last peer.
 func requestSlave(url string, jsonBody interface{}) (int, string, error) {
-	urlString := "http://" + *listenIPSlave + ":" + *listenPortSlave + url + "?&logname=lvsnetwork-master"
-	tr := &http.Transport{
-		DisableKeepAlives: true,
-	}
-	if *httpsSlave {
-		urlString = strings.ReplaceAll(urlString, "http://", "https://")
-		tr = &http.Transport{
-			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
-			DisableKeepAlives: true,
+	statuscode := http.StatusInternalServerError
+	body := ""
+	var err error
+	for _, peer := range peers {
+		statuscode, body, err = requestPeer(peer, url, jsonBody)
+		if err != nil {
+			return statuscode, body, fmt.Errorf("%v on %v", err, peer.Name) // nolint: errorlint
+		}
+		if statuscode != http.StatusOK {
+			if len(peers) > 1 {
+				body = strings.Join([]string{"[", peer.Name, "] ", body}, "")
+			}
+
+			return statuscode, body, nil
 		}
-	}
-	body := new(bytes.Buffer)
-	err := json.NewEncoder(body).Encode(jsonBody)
-	if err != nil {
-		return http.StatusInternalServerError, "", err
-	}
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, urlString, body)
-	if err != nil {
-		return http.StatusInternalServerError, "", err
-	}
-	req.Header.Add("Content-Type", "application/json; charset=utf-8")
-	client := &http.Client{Transport: tr}
-	resp, err := client.Do(req)
-	if err != nil {
-		return http.StatusInternalServerError, "", err
-	}
-	defer resp.Body.Close()
-	respBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return http.StatusInternalServerError, "", err
 	}
 
-	return resp.StatusCode, string(respBody), err
+	return statuscode, body, err
 }
 
-// requestSlaveWithoutBody : call HTTP request from MASTER to SLAVE without body.
-func requestSlaveWithoutBody(url string) (int, string, error) {
-	urlString := "http://" + *listenIPSlave + ":" + *listenPortSlave + url + "?&logname=lvsnetwork-master"
+// requestPeer : call HTTP request from MASTER to one SLAVE peer (GET if jsonBody is nil).
+func requestPeer(peer peerType, url string, jsonBody interface{}) (statuscode int, respBody string, err error) {
+	defer func(start time.Time) {
+		observeSlaveRequest(peer, start, statuscode, err)
+	}(time.Now())
+	urlString := "http://" + peer.IP + ":" + peer.Port + url + "?&logname=lvsnetwork-master"
 	tr := &http.Transport{
 		DisableKeepAlives: true,
 	}
@@ -61,22 +51,34 @@ func requestSlaveWithoutBody(url string) (int, string, error) {
 			DisableKeepAlives: true,
 		}
 	}
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, urlString, nil)
+	method := http.MethodGet
+	body := new(bytes.Buffer)
+	if jsonBody != nil {
+		method = http.MethodPost
+		err := json.NewEncoder(body).Encode(jsonBody)
+		if err != nil {
+			return http.StatusInternalServerError, "", err
+		}
+	}
+	req, err := http.NewRequestWithContext(context.Background(), method, urlString, body)
 	if err != nil {
 		return http.StatusInternalServerError, "", err
 	}
+	if jsonBody != nil {
+		req.Header.Add("Content-Type", "application/json; charset=utf-8")
+	}
 	client := &http.Client{Transport: tr}
 	resp, err := client.Do(req)
 	if err != nil {
 		return http.StatusInternalServerError, "", err
 	}
 	defer resp.Body.Close()
-	respBody, err := ioutil.ReadAll(resp.Body)
+	respByte, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return http.StatusInternalServerError, "", err
 	}
 
-	return resp.StatusCode, string(respBody), err
+	return resp.StatusCode, string(respByte), err
 }
 
 // checkIfaceSlaveExists : call /check_iface_exists/ on slave => onslaveCheckIfaceExists().
@@ -117,10 +119,10 @@ func checkIfaceSlaveOk(ifaceVrrp ifaceVrrpType) (bool, error) {
 	return false, fmt.Errorf("error on slave => %v", body)
 }
 
-// checkIfaceSlaveWithoutPostup : call /check_iface_without_postup/ on slave => onslaveCheckIfaceWithoutPostup().
-func checkIfaceSlaveWithoutPostup(ifaceVrrp ifaceVrrpType) (bool, error) {
+// checkVrrpSlaveExists : call /check_vrrp_exists/ on slave => onslaveCheckVrrpExists().
+func checkVrrpSlaveExists(ifaceVrrp ifaceVrrpType) (bool, error) {
 	statuscode, body, err := requestSlave(strings.Join([]string{
-		"/check_iface_without_postup/",
+		"/check_vrrp_exists/",
 		ifaceVrrp.Iface, "/",
 	}, ""), ifaceVrrp)
 	if (err != nil) || (statuscode == http.StatusInternalServerError) {
@@ -136,89 +138,31 @@ func checkIfaceSlaveWithoutPostup(ifaceVrrp ifaceVrrpType) (bool, error) {
 	return false, fmt.Errorf("error on slave => %v", body)
 }
 
-// addIfaceSlave : call /add_iface/ on slave => onslaveAddIface().
-func addIfaceSlave(ifaceVrrp ifaceVrrpType) error {
-	statuscode, body, err := requestSlave(strings.Join([]string{"/add_iface/", ifaceVrrp.Iface, "/"}, ""), ifaceVrrp)
-	if err != nil {
-		return err
-	}
-	if statuscode == http.StatusOK {
-		return nil
-	}
-
-	return fmt.Errorf("error on slave => %v", body)
-}
-
-// addIfaceSlaveFile : call /add_iface_file/ on slave => onslaveAddIfaceFile().
-func addIfaceSlaveFile(ifaceVrrp ifaceVrrpType) error {
-	statuscode, body, err := requestSlave(strings.Join([]string{
-		"/add_iface_file/",
-		ifaceVrrp.Iface, "/",
-	}, ""), ifaceVrrp)
-	if err != nil {
-		return err
-	}
-	if statuscode == http.StatusOK {
-		return nil
-	}
-
-	return fmt.Errorf("error on slave => %v", body)
-}
-
-// removeIfaceSlave : call /remove_iface/ on slave => onslaveRemoveIface().
-func removeIfaceSlave(ifaceVrrp ifaceVrrpType) error {
-	statuscode, body, err := requestSlave(strings.Join([]string{
-		"/remove_iface/",
-		ifaceVrrp.Iface, "/",
-	}, ""), ifaceVrrp)
-	if err != nil {
-		return err
-	}
-	if statuscode == http.StatusOK {
-		return nil
-	}
-
-	return fmt.Errorf("error on slave => %v", body)
-}
-
-// removeIfaceSlaveFile : call /remove_iface_file/ on slave => onslaveRemoveIfaceFile().
-func removeIfaceSlaveFile(ifaceVrrp ifaceVrrpType) error {
+// checkVrrpSlaveOk : call /check_vrrp_ok/ on slave => onslaveCheckVrrpOk().
+func checkVrrpSlaveOk(ifaceVrrp ifaceVrrpType) (bool, error) {
 	statuscode, body, err := requestSlave(strings.Join([]string{
-		"/remove_iface_file/",
+		"/check_vrrp_ok/",
 		ifaceVrrp.Iface, "/",
 	}, ""), ifaceVrrp)
-	if err != nil {
-		return err
-	}
-	if statuscode == http.StatusOK {
-		return nil
+	if (err != nil) || (statuscode == http.StatusInternalServerError) {
+		return false, err
 	}
-
-	return fmt.Errorf("error on slave => %v", body)
-}
-
-// changeIfaceSlavePostup : call /change_iface_postup/ on slave  => onslaveChangeIfacePostup().
-func changeIfaceSlavePostup(ifaceVrrp ifaceVrrpType) error {
-	statuscode, body, err := requestSlave(strings.Join([]string{
-		"/change_iface_postup/",
-		ifaceVrrp.Iface, "/",
-	}, ""), ifaceVrrp)
-	if err != nil {
-		return err
+	if statuscode == http.StatusNotFound {
+		return false, nil
 	}
 	if statuscode == http.StatusOK {
-		return nil
+		return true, nil
 	}
 
-	return fmt.Errorf("error on slave => %v", body)
+	return false, fmt.Errorf("error on slave => %v", body)
 }
 
-// checkVrrpSlaveExists : call /check_vrrp_exists/ on slave => onslaveCheckVrrpExists().
-func checkVrrpSlaveExists(ifaceVrrp ifaceVrrpType) (bool, error) {
+// checkVrrpScriptExistsSlave : call /check_vrrp_script_exists/ on slave => onslaveCheckVrrpScriptExists().
+func checkVrrpScriptExistsSlave(vrrpScript vrrpScriptType) (bool, error) {
 	statuscode, body, err := requestSlave(strings.Join([]string{
-		"/check_vrrp_exists/",
-		ifaceVrrp.Iface, "/",
-	}, ""), ifaceVrrp)
+		"/check_vrrp_script_exists/",
+		vrrpScript.Name, "/",
+	}, ""), vrrpScript)
 	if (err != nil) || (statuscode == http.StatusInternalServerError) {
 		return false, err
 	}
@@ -232,31 +176,31 @@ func checkVrrpSlaveExists(ifaceVrrp ifaceVrrpType) (bool, error) {
 	return false, fmt.Errorf("error on slave => %v", body)
 }
 
-// checkVrrpSlaveExistsOtherVG : call /check_vrrp_exists_otherVG/ on slave => onslaveCheckVrrpExistsOtherVG().
-func checkVrrpSlaveExistsOtherVG(ifaceVrrp ifaceVrrpType) (string, error) {
+// vrrpScriptOkSlave : call /check_vrrp_script_ok/ on slave => onslaveCheckVrrpScriptOk().
+func vrrpScriptOkSlave(vrrpScript vrrpScriptType) (bool, error) {
 	statuscode, body, err := requestSlave(strings.Join([]string{
-		"/check_vrrp_exists_otherVG/",
-		ifaceVrrp.Iface, "/",
-	}, ""), ifaceVrrp)
+		"/check_vrrp_script_ok/",
+		vrrpScript.Name, "/",
+	}, ""), vrrpScript)
 	if (err != nil) || (statuscode == http.StatusInternalServerError) {
-		return "", err
+		return false, err
 	}
 	if statuscode == http.StatusNotFound {
-		return "", nil
+		return false, nil
 	}
 	if statuscode == http.StatusOK {
-		return strings.Join(strings.Fields(body), ""), nil
+		return true, nil
 	}
 
-	return "", fmt.Errorf("error on slave => %v", body)
+	return false, fmt.Errorf("error on slave => %v", body)
 }
 
-// checkVrrpSlaveOk : call /check_vrrp_ok/ on slave => onslaveCheckVrrpOk().
-func checkVrrpSlaveOk(ifaceVrrp ifaceVrrpType) (bool, error) {
+// checkVirtualServerExistsSlave : call /check_virtual_server_exists/ on slave => onslaveCheckVirtualServerExists().
+func checkVirtualServerExistsSlave(virtualServer virtualServerType) (bool, error) {
 	statuscode, body, err := requestSlave(strings.Join([]string{
-		"/check_vrrp_ok/",
-		ifaceVrrp.Iface, "/",
-	}, ""), ifaceVrrp)
+		"/check_virtual_server_exists/",
+		virtualServer.Name, "/",
+	}, ""), virtualServer)
 	if (err != nil) || (statuscode == http.StatusInternalServerError) {
 		return false, err
 	}
@@ -270,12 +214,12 @@ func checkVrrpSlaveOk(ifaceVrrp ifaceVrrpType) (bool, error) {
 	return false, fmt.Errorf("error on slave => %v", body)
 }
 
-// checkVrrpSlaveWithoutSync : call /check_vrrp_without_sync/ on slave => onslaveCheckVrrpWithoutSync().
-func checkVrrpSlaveWithoutSync(ifaceVrrp ifaceVrrpType) (bool, error) {
+// virtualServerOkSlave : call /check_virtual_server_ok/ on slave => onslaveCheckVirtualServerOk().
+func virtualServerOkSlave(virtualServer virtualServerType) (bool, error) {
 	statuscode, body, err := requestSlave(strings.Join([]string{
-		"/check_vrrp_without_sync/",
-		ifaceVrrp.Iface, "/",
-	}, ""), ifaceVrrp)
+		"/check_virtual_server_ok/",
+		virtualServer.Name, "/",
+	}, ""), virtualServer)
 	if (err != nil) || (statuscode == http.StatusInternalServerError) {
 		return false, err
 	}
@@ -289,130 +233,320 @@ func checkVrrpSlaveWithoutSync(ifaceVrrp ifaceVrrpType) (bool, error) {
 	return false, fmt.Errorf("error on slave => %v", body)
 }
 
-// syncGroupAndReloadSlave : call /sync_group_reload_vrrp/ on slave => onslaveSyncGroupAndReload().
-func syncGroupAndReloadSlave() error {
-	statuscode, body, err := requestSlaveWithoutBody("/sync_group_reload_vrrp/")
+// readInventoryPeer : call /list/ on one slave peer => onslaveList().
+func readInventoryPeer(peer peerType) (inventoryType, error) {
+	var inventory inventoryType
+	statuscode, body, err := requestPeer(peer, "/list/", nil)
 	if err != nil {
-		return err
+		return inventory, err
 	}
-	if statuscode == http.StatusOK {
-		return nil
+	if statuscode != http.StatusOK {
+		return inventory, fmt.Errorf("error on slave %v => %v", peer.Name, body)
+	}
+	err = json.Unmarshal([]byte(body), &inventory)
+	if err != nil {
+		return inventory, err
 	}
 
-	return fmt.Errorf("error on slave => %v", body)
+	return inventory, nil
 }
 
-// reloadVrrpSlave : call /reload_vrrp/ on slave => onslaveReloadVrrp().
-func reloadVrrpSlave() error {
-	statuscode, body, err := requestSlaveWithoutBody("/reload_vrrp/")
+// readVrrpStatesPeer : call /state_vrrp/ on one slave peer => onslaveStateVrrp().
+func readVrrpStatesPeer(peer peerType) ([]vrrpStateType, error) {
+	var vrrpStates []vrrpStateType
+	statuscode, body, err := requestPeer(peer, "/state_vrrp/", nil)
 	if err != nil {
-		return err
+		return vrrpStates, err
 	}
-	if statuscode == http.StatusOK {
-		return nil
+	if statuscode != http.StatusOK {
+		return vrrpStates, fmt.Errorf("error on slave %v => %v", peer.Name, body)
+	}
+	err = json.Unmarshal([]byte(body), &vrrpStates)
+	if err != nil {
+		return vrrpStates, err
 	}
 
-	return fmt.Errorf("error on slave => %v", body)
+	return vrrpStates, nil
 }
 
-// addVrrpSlave : call /add_vrrp/ on slave => onslaveAddVrrp().
-func addVrrpSlave(ifaceVrrp ifaceVrrpType) error {
-	statuscode, body, err := requestSlave(strings.Join([]string{
-		"/add_vrrp/",
-		ifaceVrrp.Iface, "/",
-	}, ""), ifaceVrrp)
+// readNotificationsPeer : call /notify_history/ on one slave peer => onslaveNotifyHistory().
+func readNotificationsPeer(peer peerType) ([]notifyType, error) {
+	var notifications []notifyType
+	statuscode, body, err := requestPeer(peer, "/notify_history/", nil)
 	if err != nil {
-		return err
+		return notifications, err
 	}
-	if statuscode == http.StatusOK {
-		return nil
+	if statuscode != http.StatusOK {
+		return notifications, fmt.Errorf("error on slave %v => %v", peer.Name, body)
+	}
+	err = json.Unmarshal([]byte(body), &notifications)
+	if err != nil {
+		return notifications, err
 	}
 
-	return fmt.Errorf("error on slave => %v", body)
+	return notifications, nil
 }
 
-// removeVrrpSlave : call /remove_vrrp/ on slave => onslaveRemoveVrrp().
-func removeVrrpSlave(ifaceVrrp ifaceVrrpType) error {
-	statuscode, body, err := requestSlave(strings.Join([]string{
-		"/remove_vrrp/",
-		ifaceVrrp.Iface, "/",
-	}, ""), ifaceVrrp)
+// checkPeer : call a check url on one slave peer, return false if not found.
+func checkPeer(peer peerType, url string, jsonBody interface{}) (bool, string, error) {
+	statuscode, body, err := requestPeer(peer, url, jsonBody)
 	if err != nil {
-		return err
+		return false, "", fmt.Errorf("%v on %v", err, peer.Name) // nolint: errorlint
 	}
-	if statuscode == http.StatusOK {
-		return nil
+	if statuscode == http.StatusNotFound {
+		return false, "", nil
+	}
+	if statuscode != http.StatusOK {
+		return false, "", fmt.Errorf("error on slave %v => %v", peer.Name, body)
 	}
 
-	return fmt.Errorf("error on slave => %v", body)
+	return true, body, nil
 }
 
-// checkVrrpScriptExistsSlave : call /check_vrrp_script_exists/ on slave => onslaveCheckVrrpScriptExists().
-func checkVrrpScriptExistsSlave(vrrpScript vrrpScriptType) (bool, error) {
-	statuscode, body, err := requestSlave(strings.Join([]string{
+// checkIfacePeer : call /check_iface_exists/, /check_iface_ok/ or /check_iface_without_postup/ on one slave peer,
+// return false if not found.
+func checkIfacePeer(peer peerType, url string, ifaceVrrp ifaceVrrpType) (bool, error) {
+	ok, _, err := checkPeer(peer, strings.Join([]string{
+		url,
+		ifaceVrrp.Iface, "/",
+	}, ""), ifaceVrrp)
+
+	return ok, err
+}
+
+// checkIfacePeerExists : call /check_iface_exists/ on one slave peer => onslaveCheckIfaceExists().
+func checkIfacePeerExists(peer peerType, ifaceVrrp ifaceVrrpType) (bool, error) {
+	return checkIfacePeer(peer, "/check_iface_exists/", ifaceVrrp)
+}
+
+// checkIfacePeerOk : call /check_iface_ok/ on one slave peer => onslaveCheckIfaceOk().
+func checkIfacePeerOk(peer peerType, ifaceVrrp ifaceVrrpType) (bool, error) {
+	return checkIfacePeer(peer, "/check_iface_ok/", ifaceVrrp)
+}
+
+// checkIfacePeerWithoutPostup : call /check_iface_without_postup/ on one slave peer
+// => onslaveCheckIfaceWithoutPostup().
+func checkIfacePeerWithoutPostup(peer peerType, ifaceVrrp ifaceVrrpType) (bool, error) {
+	return checkIfacePeer(peer, "/check_iface_without_postup/", ifaceVrrp)
+}
+
+// checkVrrpPeer : call /check_vrrp_exists/, /check_vrrp_exists_otherVG/, /check_vrrp_ok/
+// or /check_vrrp_without_sync/ on one slave peer,
+// return false if not found.
+func checkVrrpPeer(peer peerType, url string, ifaceVrrp ifaceVrrpType) (bool, string, error) {
+	return checkPeer(peer, strings.Join([]string{
+		url,
+		ifaceVrrp.Iface, "/",
+	}, ""), ifaceVrrp)
+}
+
+// checkVrrpPeerExists : call /check_vrrp_exists/ on one slave peer => onslaveCheckVrrpExists().
+func checkVrrpPeerExists(peer peerType, ifaceVrrp ifaceVrrpType) (bool, error) {
+	exists, _, err := checkVrrpPeer(peer, "/check_vrrp_exists/", ifaceVrrp)
+
+	return exists, err
+}
+
+// checkVrrpPeerExistsOtherVG : call /check_vrrp_exists_otherVG/ on one slave peer => onslaveCheckVrrpExistsOtherVG(),
+// return the other Vrrp_group or empty string.
+func checkVrrpPeerExistsOtherVG(peer peerType, ifaceVrrp ifaceVrrpType) (string, error) {
+	_, body, err := checkVrrpPeer(peer, "/check_vrrp_exists_otherVG/", ifaceVrrp)
+
+	return strings.Join(strings.Fields(body), ""), err
+}
+
+// checkVrrpPeerOk : call /check_vrrp_ok/ on one slave peer => onslaveCheckVrrpOk().
+func checkVrrpPeerOk(peer peerType, ifaceVrrp ifaceVrrpType) (bool, error) {
+	ok, _, err := checkVrrpPeer(peer, "/check_vrrp_ok/", ifaceVrrp)
+
+	return ok, err
+}
+
+// checkVrrpScriptPeerExists : call /check_vrrp_script_exists/ on one slave peer => onslaveCheckVrrpScriptExists().
+func checkVrrpScriptPeerExists(peer peerType, vrrpScript vrrpScriptType) (bool, error) {
+	exists, _, err := checkPeer(peer, strings.Join([]string{
 		"/check_vrrp_script_exists/",
 		vrrpScript.Name, "/",
 	}, ""), vrrpScript)
-	if (err != nil) || (statuscode == http.StatusInternalServerError) {
-		return false, err
-	}
-	if statuscode == http.StatusNotFound {
-		return false, nil
-	}
-	if statuscode == http.StatusOK {
-		return true, nil
-	}
 
-	return false, fmt.Errorf("error on slave => %v", body)
+	return exists, err
 }
 
-// vrrpScriptOkSlave : call /check_vrrp_script_ok/ on slave => onslaveCheckVrrpScriptOk().
-func vrrpScriptOkSlave(vrrpScript vrrpScriptType) (bool, error) {
-	statuscode, body, err := requestSlave(strings.Join([]string{
+// checkVrrpScriptPeerOk : call /check_vrrp_script_ok/ on one slave peer => onslaveCheckVrrpScriptOk().
+func checkVrrpScriptPeerOk(peer peerType, vrrpScript vrrpScriptType) (bool, error) {
+	ok, _, err := checkPeer(peer, strings.Join([]string{
 		"/check_vrrp_script_ok/",
 		vrrpScript.Name, "/",
 	}, ""), vrrpScript)
-	if (err != nil) || (statuscode == http.StatusInternalServerError) {
-		return false, err
+
+	return ok, err
+}
+
+// checkVirtualServerPeerExists : call /check_virtual_server_exists/ on one slave peer
+// => onslaveCheckVirtualServerExists().
+func checkVirtualServerPeerExists(peer peerType, virtualServer virtualServerType) (bool, error) {
+	exists, _, err := checkPeer(peer, strings.Join([]string{
+		"/check_virtual_server_exists/",
+		virtualServer.Name, "/",
+	}, ""), virtualServer)
+
+	return exists, err
+}
+
+// checkVirtualServerPeerOk : call /check_virtual_server_ok/ on one slave peer => onslaveCheckVirtualServerOk().
+func checkVirtualServerPeerOk(peer peerType, virtualServer virtualServerType) (bool, error) {
+	ok, _, err := checkPeer(peer, strings.Join([]string{
+		"/check_virtual_server_ok/",
+		virtualServer.Name, "/",
+	}, ""), virtualServer)
+
+	return ok, err
+}
+
+// checkVrrpPeerWithoutSync : call /check_vrrp_without_sync/ on one slave peer => onslaveCheckVrrpWithoutSync().
+func checkVrrpPeerWithoutSync(peer peerType, ifaceVrrp ifaceVrrpType) (bool, error) {
+	ok, _, err := checkVrrpPeer(peer, "/check_vrrp_without_sync/", ifaceVrrp)
+
+	return ok, err
+}
+
+// readIfaceVrrpPeer : call /read_iface_vrrp/ on one slave peer => onslaveReadIfaceVrrp().
+func readIfaceVrrpPeer(peer peerType, iface string) (ifaceVrrpType, bool, error) {
+	var ifaceVrrpRead ifaceVrrpType
+	statuscode, body, err := requestPeer(peer, strings.Join([]string{
+		"/read_iface_vrrp/",
+		iface, "/",
+	}, ""), nil)
+	if err != nil {
+		return ifaceVrrpRead, false, err
 	}
 	if statuscode == http.StatusNotFound {
-		return false, nil
+		return ifaceVrrpRead, false, nil
 	}
-	if statuscode == http.StatusOK {
-		return true, nil
+	if statuscode != http.StatusOK {
+		return ifaceVrrpRead, false, fmt.Errorf("error on slave %v => %v", peer.Name, body)
+	}
+	err = json.Unmarshal([]byte(body), &ifaceVrrpRead)
+	if err != nil {
+		return ifaceVrrpRead, false, err
 	}
 
-	return false, fmt.Errorf("error on slave => %v", body)
+	return ifaceVrrpRead, true, nil
 }
 
-// addVrrpScriptSlave : call /add_vrrp_script/ on slave => onslaveAddVrrpScript().
-func addVrrpScriptSlave(vrrpScript vrrpScriptType) error {
-	statuscode, body, err := requestSlave(strings.Join([]string{
-		"/add_vrrp_script/",
-		vrrpScript.Name, "/",
-	}, ""), vrrpScript)
+// diffPeer : call /diff_iface_vrrp/, /diff_vrrp_script/ or /diff_virtual_server/ on one slave peer
+// => onslaveDiffIfaceVrrp(), onslaveDiffVrrpScript() or onslaveDiffVirtualServer().
+func diffPeer(peer peerType, url string, jsonBody interface{}) (diffType, error) {
+	var diff diffType
+	statuscode, body, err := requestPeer(peer, url, jsonBody)
 	if err != nil {
-		return err
+		return diff, err
 	}
-	if statuscode == http.StatusOK {
-		return nil
+	if statuscode != http.StatusOK {
+		return diff, fmt.Errorf("error on slave %v => %v", peer.Name, body)
+	}
+	err = json.Unmarshal([]byte(body), &diff)
+	if err != nil {
+		return diff, err
 	}
 
-	return fmt.Errorf("error on slave => %v", body)
+	return diff, nil
 }
 
-// removeVrrpScriptSlave : call /remove_vrrp_script/ on slave => onslaveRemoveVrrpScript().
-func removeVrrpScriptSlave(vrrpScript vrrpScriptType) error {
-	statuscode, body, err := requestSlave(strings.Join([]string{
-		"/remove_vrrp_script/",
-		vrrpScript.Name, "/",
-	}, ""), vrrpScript)
+// diffPeers : call diffPeer() on each slave peer, diff of first peer in Slave and each peer in Peers if more than one.
+func diffPeers(diffReport *diffReportType, url string, jsonBody interface{}) error {
+	for i, peer := range peers {
+		diff, err := diffPeer(peer, url, jsonBody)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			diffReport.Slave = diff
+		}
+		if len(peers) > 1 {
+			if diffReport.Peers == nil {
+				diffReport.Peers = make(map[string]diffType)
+			}
+			diffReport.Peers[peer.Name] = diff
+		}
+	}
+
+	return nil
+}
+
+// requestPeerOk : call HTTP request from MASTER to one SLAVE peer, error if response isn't OK.
+func requestPeerOk(peer peerType, url string, jsonBody interface{}) error {
+	statuscode, body, err := requestPeer(peer, url, jsonBody)
 	if err != nil {
-		return err
+		return fmt.Errorf("%v on %v", err, peer.Name) // nolint: errorlint
 	}
-	if statuscode == http.StatusOK {
-		return nil
+	if statuscode != http.StatusOK {
+		return fmt.Errorf("error on slave %v => %v", peer.Name, body)
+	}
+
+	return nil
+}
+
+// readManagedFilePeer : call /get_file/ on one slave peer => onslaveGetFile().
+func readManagedFilePeer(peer peerType, path string) (managedFileType, error) {
+	file := managedFileType{
+		Path: path,
+	}
+	statuscode, body, err := requestPeer(peer, "/get_file/", file)
+	if err != nil {
+		return file, err
+	}
+	if statuscode != http.StatusOK {
+		return file, fmt.Errorf("error on slave %v => %v", peer.Name, body)
+	}
+	err = json.Unmarshal([]byte(body), &file)
+	if err != nil {
+		return file, err
+	}
+
+	return file, nil
+}
+
+// writeManagedFilePeer : call /put_file/ on one slave peer => onslavePutFile().
+func writeManagedFilePeer(peer peerType, file managedFileType) error {
+	return requestPeerOk(peer, "/put_file/", file)
+}
+
+// readManagedFilesPeer : call /get_files/ on one slave peer => onslaveGetFiles().
+func readManagedFilesPeer(peer peerType) ([]managedFileType, error) {
+	var files []managedFileType
+	statuscode, body, err := requestPeer(peer, "/get_files/", nil)
+	if err != nil {
+		return files, err
+	}
+	if statuscode != http.StatusOK {
+		return files, fmt.Errorf("error on slave %v => %v", peer.Name, body)
+	}
+	err = json.Unmarshal([]byte(body), &files)
+	if err != nil {
+		return files, err
+	}
+
+	return files, nil
+}
+
+// generateIfaceVrrpPeer : call /generate_iface_vrrp/ on one slave peer => onslaveGenerateIfaceVrrp().
+func generateIfaceVrrpPeer(peer peerType, ifaceVrrp ifaceVrrpType) ([]managedFileType, error) {
+	var files []managedFileType
+	statuscode, body, err := requestPeer(peer, strings.Join([]string{
+		"/generate_iface_vrrp/",
+		ifaceVrrp.Iface, "/",
+	}, ""), ifaceVrrp)
+	if err != nil {
+		return files, err
+	}
+	if statuscode != http.StatusOK {
+		return files, fmt.Errorf("error on slave %v => %v", peer.Name, body)
+	}
+	err = json.Unmarshal([]byte(body), &files)
+	if err != nil {
+		return files, err
 	}
 
-	return fmt.Errorf("error on slave => %v", body)
+	return files, nil
 }
@@ -0,0 +1,1128 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// stepType : one step of a plan with function for apply and function for revert (undo can be nil,
+// it's also called if do failed so it must revert a partial do), node, files and commands are only for dry run report.
+type stepType struct {
+	name     string
+	node     string
+	write    []managedFileType
+	remove   []string
+	commands []string
+	do       func() error
+	undo     func() error
+}
+
+// planType : ordered list of reversible steps on master & slave servers.
+type planType struct {
+	dryRun   bool
+	steps    []stepType
+	progress func(string)
+}
+
+// add : append a step at the end of plan.
+func (plan *planType) add(name string, do, undo func() error) {
+	plan.steps = append(plan.steps, stepType{name: name, do: do, undo: undo})
+}
+
+// describe : set node, files written, files removed and commands of last step added.
+func (plan *planType) describe(node string, write []managedFileType, remove, commands []string) {
+	step := &plan.steps[len(plan.steps)-1]
+	step.node = node
+	step.write = write
+	step.remove = remove
+	step.commands = commands
+}
+
+// report : list of steps and by node files written, files removed and commands, without apply (dry run).
+func (plan *planType) report() dryRunType {
+	report := dryRunType{
+		Steps: make([]string, 0),
+		Nodes: make(map[string]dryRunNodeType),
+	}
+	for _, step := range plan.steps {
+		report.Steps = append(report.Steps, step.name)
+		if step.node == "" {
+			continue
+		}
+		nodeReport, ok := report.Nodes[step.node]
+		if !ok {
+			nodeReport = dryRunNodeType{
+				Write:    make([]managedFileType, 0),
+				Remove:   make([]string, 0),
+				Commands: make([]string, 0),
+			}
+		}
+		nodeReport.Write = append(nodeReport.Write, step.write...)
+		nodeReport.Remove = append(nodeReport.Remove, step.remove...)
+		nodeReport.Commands = append(nodeReport.Commands, step.commands...)
+		report.Nodes[step.node] = nodeReport
+	}
+
+	return report
+}
+
+// generatedFile : file generated on node with ifaceVrrp for dry run report (only path if not dry run).
+func (plan *planType) generatedFile(node nodeOpsType, ifaceVrrp ifaceVrrpType, path string) (managedFileType, error) {
+	file := managedFileType{
+		Exists: true,
+		Path:   path,
+	}
+	if !plan.dryRun {
+		return file, nil
+	}
+	files, err := node.generateIfaceVrrp(ifaceVrrp)
+	if err != nil {
+		return file, err
+	}
+	for _, fileGenerated := range files {
+		if fileGenerated.Path == path {
+			return fileGenerated, nil
+		}
+	}
+
+	return file, nil
+}
+
+// reloadCommands : reload_cmd repeated 'count' times for dry run report.
+func reloadCommands(count int) []string {
+	commands := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		commands = append(commands, configReloadCommand())
+	}
+
+	return commands
+}
+
+// run : apply each step in order, if a step failed revert it (do can be partial) and steps already done
+// in reverse order for stay on old configuration.
+func (plan *planType) run() error {
+	for i, step := range plan.steps {
+		err := step.do()
+		if err != nil {
+			errRollback := plan.rollback(i + 1)
+			if errRollback != nil {
+				return fmt.Errorf("%v : %v (rollback failed : %v)", step.name, err, errRollback) // nolint: errorlint
+			}
+
+			return fmt.Errorf("%v : %v (rollback done)", step.name, err) // nolint: errorlint
+		}
+		if plan.progress != nil {
+			plan.progress(step.name)
+		}
+	}
+
+	return nil
+}
+
+// rollback : call undo of the 'done' first steps in reverse order (the last can be partially done),
+// continue on error for revert a maximum.
+func (plan *planType) rollback(done int) error {
+	var errs []string
+	for i := done - 1; i >= 0; i-- {
+		if plan.steps[i].undo == nil {
+			continue
+		}
+		err := plan.steps[i].undo()
+		if err != nil {
+			errs = append(errs, strings.Join([]string{plan.steps[i].name, err.Error()}, " : "))
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+
+	return nil
+}
+
+// nodeOpsType : functions for apply configuration on master or on one slave peer.
+type nodeOpsType struct {
+	master             bool
+	name               string
+	addIface           func(ifaceVrrpType) error
+	removeIface        func(ifaceVrrpType) error
+	ifupIface          func(string) error
+	ifdownIface        func(ifaceVrrpType) error
+	addIfaceFile       func(ifaceVrrpType) error
+	removeIfaceFile    func(ifaceVrrpType) error
+	changeIfacePostup  func(ifaceVrrpType) error
+	addVrrp            func(ifaceVrrpType) error
+	removeVrrp         func(ifaceVrrpType) error
+	reloadVrrp         func() error
+	syncGroupAndReload func() error
+	waitVrrp           func(ifaceVrrpType) error
+	waitVrrpRemoved    func(ifaceVrrpType) error
+	waitKeepalived     func() error
+	readVrrpStates     func() ([]vrrpStateType, error)
+	generateIfaceVrrp  func(ifaceVrrpType) ([]managedFileType, error)
+	readFile           func(string) (managedFileType, error)
+	readFiles          func() ([]managedFileType, error)
+	writeFile          func(managedFileType) error
+}
+
+// masterOps : operations on master (local functions).
+func masterOps() nodeOpsType {
+	return nodeOpsType{
+		master:             true,
+		name:               "master",
+		addIface:           addIface,
+		removeIface:        removeIface,
+		ifupIface:          ifupIface,
+		ifdownIface:        ifdownIface,
+		addIfaceFile:       addIfaceFile,
+		removeIfaceFile:    removeIfaceFile,
+		changeIfacePostup:  changeIfacePostup,
+		addVrrp:            addVrrp,
+		removeVrrp:         removeVrrp,
+		reloadVrrp:         reloadVrrp,
+		syncGroupAndReload: syncGroupAndReload,
+		waitVrrp:           waitVrrpReady,
+		waitVrrpRemoved:    waitVrrpRemoved,
+		waitKeepalived:     waitKeepalivedReady,
+		readVrrpStates:     readVrrpStates,
+		generateIfaceVrrp:  generateIfaceVrrpFiles,
+		readFile:           readManagedFile,
+		readFiles:          readManagedFiles,
+		writeFile:          writeManagedFile,
+	}
+}
+
+// peerOps : operations on one slave peer (request on slave API).
+func peerOps(peer peerType) nodeOpsType {
+	ifaceRequest := func(url string) func(ifaceVrrpType) error {
+		return func(ifaceVrrp ifaceVrrpType) error {
+			return requestPeerOk(peer, strings.Join([]string{url, ifaceVrrp.Iface, "/"}, ""), ifaceVrrp)
+		}
+	}
+
+	return nodeOpsType{
+		master:      false,
+		name:        peer.Name,
+		addIface:    ifaceRequest("/add_iface/"),
+		removeIface: ifaceRequest("/remove_iface/"),
+		ifupIface: func(iface string) error {
+			return requestPeerOk(peer, strings.Join([]string{"/ifup/", iface, "/"}, ""), nil)
+		},
+		ifdownIface:       ifaceRequest("/ifdown/"),
+		addIfaceFile:      ifaceRequest("/add_iface_file/"),
+		removeIfaceFile:   ifaceRequest("/remove_iface_file/"),
+		changeIfacePostup: ifaceRequest("/change_iface_postup/"),
+		addVrrp:           ifaceRequest("/add_vrrp/"),
+		removeVrrp:        ifaceRequest("/remove_vrrp/"),
+		reloadVrrp: func() error {
+			return requestPeerOk(peer, "/reload_vrrp/", nil)
+		},
+		syncGroupAndReload: func() error {
+			return requestPeerOk(peer, "/sync_group_reload_vrrp/", nil)
+		},
+		waitVrrp:        ifaceRequest("/wait_vrrp/"),
+		waitVrrpRemoved: ifaceRequest("/wait_vrrp_removed/"),
+		waitKeepalived: func() error {
+			return requestPeerOk(peer, "/wait_keepalived/", nil)
+		},
+		readVrrpStates: func() ([]vrrpStateType, error) {
+			return readVrrpStatesPeer(peer)
+		},
+		generateIfaceVrrp: func(ifaceVrrp ifaceVrrpType) ([]managedFileType, error) {
+			return generateIfaceVrrpPeer(peer, ifaceVrrp)
+		},
+		readFile: func(path string) (managedFileType, error) {
+			return readManagedFilePeer(peer, path)
+		},
+		readFiles: func() ([]managedFileType, error) {
+			return readManagedFilesPeer(peer)
+		},
+		writeFile: func(file managedFileType) error {
+			return writeManagedFilePeer(peer, file)
+		},
+	}
+}
+
+// nodesOps : operations on master then on each slave peer.
+func nodesOps() []nodeOpsType {
+	nodes := []nodeOpsType{masterOps()}
+	for _, peer := range peers {
+		nodes = append(nodes, peerOps(peer))
+	}
+
+	return nodes
+}
+
+// addIfaceSteps : add step for create network config and ifup on node if file doesn't exist,
+// undo is ifdown and remove file.
+func (plan *planType) addIfaceSteps(node nodeOpsType, ifaceVrrp ifaceVrrpType) error {
+	saved, err := node.readFile(ifaceFilePath(ifaceVrrp.Iface))
+	if err != nil {
+		return err
+	}
+	if saved.Exists {
+		return nil
+	}
+	plan.add(strings.Join([]string{"add iface on", node.name}, " "),
+		func() error {
+			return node.addIface(ifaceVrrp)
+		},
+		func() error {
+			current, err := node.readFile(saved.Path)
+			if err != nil {
+				return err
+			}
+			if !current.Exists {
+				// add failed before write of file
+				return nil
+			}
+			err = node.removeIface(ifaceVrrp)
+			if err != nil {
+				// ifup failed before post-up commands, ifdown and remove file without them
+				ifaceVrrpWithoutPostUp := ifaceVrrp
+				ifaceVrrpWithoutPostUp.PostUp = nil
+
+				return node.removeIface(ifaceVrrpWithoutPostUp)
+			}
+
+			return nil
+		})
+	ifaceFile, err := plan.generatedFile(node, ifaceVrrp, saved.Path)
+	if err != nil {
+		return err
+	}
+	plan.describe(node.name, []managedFileType{ifaceFile}, nil, []string{strings.Join([]string{"ifup", ifaceVrrp.Iface}, " ")})
+
+	return nil
+}
+
+// changeIfaceSteps : add step for change post-up and network config file on node,
+// undo is change post-up to old values and restore old file.
+func (plan *planType) changeIfaceSteps(node nodeOpsType, ifaceVrrp ifaceVrrpType) error {
+	saved, err := node.readFile(ifaceFilePath(ifaceVrrp.Iface))
+	if err != nil {
+		return err
+	}
+	ifaceRead, err := parseIfaceFile(ifaceVrrp.Iface, saved.Content)
+	if err != nil {
+		return err
+	}
+	ifaceVrrpOld := ifaceVrrp
+	ifaceVrrpOld.PostUp = ifaceRead.PostUp
+	plan.add(strings.Join([]string{"change iface on", node.name}, " "),
+		func() error {
+			err := node.changeIfacePostup(ifaceVrrp)
+			if err != nil {
+				return err
+			}
+			err = node.removeIfaceFile(ifaceVrrp)
+			if err != nil {
+				return err
+			}
+			err = node.addIfaceFile(ifaceVrrp)
+			if err != nil {
+				// file removed, restore it before return
+				errRestore := node.writeFile(saved)
+				if errRestore != nil {
+					return fmt.Errorf("%v %v", err, errRestore) // nolint: errorlint
+				}
+
+				return err
+			}
+
+			return nil
+		},
+		func() error {
+			err := node.changeIfacePostup(ifaceVrrpOld)
+			if err != nil {
+				return err
+			}
+
+			return node.writeFile(saved)
+		})
+	ifaceFile, err := plan.generatedFile(node, ifaceVrrp, saved.Path)
+	if err != nil {
+		return err
+	}
+	plan.describe(node.name, []managedFileType{ifaceFile}, nil, postUpCommands(ifaceVrrpOld.PostUp, ifaceVrrp.PostUp))
+
+	return nil
+}
+
+// removeIfaceSteps : add step for ifdown and remove network config file on node if file exists,
+// undo is restore old file and ifup.
+func (plan *planType) removeIfaceSteps(node nodeOpsType, ifaceVrrp ifaceVrrpType) error {
+	saved, err := node.readFile(ifaceFilePath(ifaceVrrp.Iface))
+	if err != nil {
+		return err
+	}
+	if !saved.Exists {
+		return nil
+	}
+	plan.add(strings.Join([]string{"remove iface on", node.name}, " "),
+		func() error {
+			return node.removeIface(ifaceVrrp)
+		},
+		func() error {
+			err := node.writeFile(saved)
+			if err != nil {
+				return err
+			}
+
+			return node.ifupIface(ifaceVrrp.Iface)
+		})
+	commands := append(postUpCommands(ifaceVrrp.PostUp, nil), strings.Join([]string{"ifdown", ifaceVrrp.Iface, "--force"}, " "))
+	plan.describe(node.name, nil, []string{saved.Path}, commands)
+
+	return nil
+}
+
+// postUpCommands : commands executed for change post-up from old to new list (add new, del old route/rule).
+func postUpCommands(postUpOld, postUpNew []string) []string {
+	commands := make([]string, 0)
+	for _, postupNew := range postUpNew {
+		if !stringInSlice(postupNew, postUpOld) {
+			commands = append(commands, postupNew)
+		}
+	}
+	for _, postupOld := range postUpOld {
+		if !stringInSlice(postupOld, postUpNew) {
+			if postdown := postDown(postupOld); postdown != "" {
+				commands = append(commands, postdown)
+			}
+		}
+	}
+
+	return commands
+}
+
+// checkVlanComStep : add step for check L2 communication between master and slave peers (no undo).
+func (plan *planType) checkVlanComStep(ifaceVrrp ifaceVrrpType) {
+	plan.add("check communication",
+		func() error {
+			return checkVlanCom(ifaceVrrp)
+		}, nil)
+	commands := make([]string, 0)
+	for _, peer := range peers {
+		if strings.Contains(peerIP(ifaceVrrp, peer), ":") {
+			commands = append(commands, strings.Join([]string{"ping6 -c1 -t1", peerIP(ifaceVrrp, peer)}, " "))
+		} else {
+			commands = append(commands, strings.Join([]string{"ping -c1 -t1", peerIP(ifaceVrrp, peer)}, " "))
+		}
+	}
+	plan.describe("master", nil, nil, commands)
+}
+
+// changeVrrpSteps : add steps for write vrrp config file (and remove old file of ifaceVrrpRm if Vrrp_group isn't empty)
+// then reload keepalived on node, undo is restore old files and reload keepalived.
+func (plan *planType) changeVrrpSteps(node nodeOpsType, ifaceVrrp, ifaceVrrpRm ifaceVrrpType) error {
+	saved, err := node.readFile(vrrpFilePath(ifaceVrrp))
+	if err != nil {
+		return err
+	}
+	savedFiles := []managedFileType{saved}
+	if ifaceVrrpRm.VrrpGroup != "" && vrrpFilePath(ifaceVrrpRm) != saved.Path {
+		savedRm, err := node.readFile(vrrpFilePath(ifaceVrrpRm))
+		if err != nil {
+			return err
+		}
+		savedFiles = append(savedFiles, savedRm)
+	}
+	plan.add(strings.Join([]string{"write vrrp on", node.name}, " "),
+		func() error {
+			if ifaceVrrpRm.VrrpGroup != "" {
+				err := node.removeVrrp(ifaceVrrpRm)
+				if err != nil {
+					return err
+				}
+			}
+
+			return node.addVrrp(ifaceVrrp)
+		},
+		func() error {
+			for _, file := range savedFiles {
+				err := node.writeFile(file)
+				if err != nil {
+					return err
+				}
+			}
+
+			return node.syncGroupAndReload()
+		})
+	vrrpFile, err := plan.generatedFile(node, ifaceVrrp, saved.Path)
+	if err != nil {
+		return err
+	}
+	var removeFiles []string
+	if ifaceVrrpRm.VrrpGroup != "" && vrrpFilePath(ifaceVrrpRm) != saved.Path {
+		removeFiles = append(removeFiles, vrrpFilePath(ifaceVrrpRm))
+	}
+	plan.describe(node.name, []managedFileType{vrrpFile}, removeFiles, nil)
+	plan.reloadVrrpStep(node, ifaceVrrp, !saved.Exists)
+
+	return nil
+}
+
+// removeVrrpSteps : add step for remove vrrp config file and reload keepalived on node if file exists,
+// undo is restore old file and reload keepalived.
+func (plan *planType) removeVrrpSteps(node nodeOpsType, ifaceVrrp ifaceVrrpType) error {
+	saved, err := node.readFile(vrrpFilePath(ifaceVrrp))
+	if err != nil {
+		return err
+	}
+	if !saved.Exists {
+		return nil
+	}
+	plan.add(strings.Join([]string{"remove vrrp on", node.name}, " "),
+		func() error {
+			err := node.removeVrrp(ifaceVrrp)
+			if err != nil {
+				return err
+			}
+			err = node.syncGroupAndReload()
+			if err != nil {
+				return err
+			}
+
+			return node.waitVrrpRemoved(ifaceVrrp)
+		},
+		func() error {
+			err := node.writeFile(saved)
+			if err != nil {
+				return err
+			}
+
+			return node.syncGroupAndReload()
+		})
+	plan.describe(node.name, nil, []string{saved.Path}, reloadCommands(1))
+
+	return nil
+}
+
+// reloadVrrpStep : add step for reload keepalived on node after write vrrp config file (no undo).
+func (plan *planType) reloadVrrpStep(node nodeOpsType, ifaceVrrp ifaceVrrpType, newVrrp bool) {
+	plan.add(strings.Join([]string{"reload vrrp on", node.name}, " "),
+		func() error {
+			if node.master || newVrrp {
+				err := node.reloadVrrp()
+				if err != nil {
+					return err
+				}
+				// reload twice for vmac up before add IP (bug keepalived)
+				// reload twice for new vrrp comme up
+				err = node.waitKeepalived()
+				if err != nil {
+					return err
+				}
+			}
+			err := node.syncGroupAndReload()
+			if err != nil {
+				return err
+			}
+			// reload twice for vmac up before add IP (bug keepalived)
+			if !node.master && !newVrrp && ifaceVrrp.UseVmac {
+				err = node.waitVrrp(ifaceVrrp)
+				if err != nil {
+					return err
+				}
+				err = node.syncGroupAndReload()
+				if err != nil {
+					return err
+				}
+			}
+
+			return node.waitVrrp(ifaceVrrp)
+		}, nil)
+	reloadCount := 1
+	if node.master || newVrrp {
+		reloadCount++
+	}
+	if !node.master && !newVrrp && ifaceVrrp.UseVmac {
+		reloadCount++
+	}
+	plan.describe(node.name, nil, nil, reloadCommands(reloadCount))
+}
+
+// addVrrpSteps : add steps for write vrrp config file if it doesn't exist and reload keepalived on node.
+func (plan *planType) addVrrpSteps(node nodeOpsType, ifaceVrrp ifaceVrrpType) error {
+	saved, err := node.readFile(vrrpFilePath(ifaceVrrp))
+	if err != nil {
+		return err
+	}
+	if saved.Exists {
+		plan.add(strings.Join([]string{"reload vrrp on", node.name}, " "),
+			func() error {
+				err := node.syncGroupAndReload()
+				if err != nil {
+					return err
+				}
+
+				return node.waitVrrp(ifaceVrrp)
+			}, nil)
+		plan.describe(node.name, nil, nil, reloadCommands(1))
+
+		return nil
+	}
+
+	return plan.changeVrrpSteps(node, ifaceVrrp, ifaceVrrpType{})
+}
+
+// restoreFilesSteps : add steps for apply files of snapshot on node and remove managed files not in snapshot :
+// keepalived files first (read only on reload), ifdown and remove of iface not in snapshot, ifdown and ifup
+// of changed iface, ifup of new iface, then reload keepalived and wait vrrp instances restored.
+// Undo is restore current files (ifdown, ifup) and reload keepalived.
+func (plan *planType) restoreFilesSteps(node nodeOpsType, files []managedFileType) error {
+	currentFiles, err := node.readFiles()
+	if err != nil {
+		return err
+	}
+	saved := make(map[string]managedFileType)
+	restored := make(map[string]managedFileType)
+	paths := make([]string, 0)
+	for _, file := range currentFiles {
+		saved[file.Path] = file
+		paths = append(paths, file.Path)
+	}
+	for _, file := range files {
+		if !file.Exists {
+			continue
+		}
+		if _, ok := saved[file.Path]; !ok {
+			paths = append(paths, file.Path)
+		}
+		restored[file.Path] = file
+	}
+	sort.Strings(paths)
+	// first step only for reload keepalived at the end of rollback
+	plan.add(strings.Join([]string{"restore files on", node.name}, " "),
+		func() error {
+			return nil
+		},
+		func() error {
+			return node.syncGroupAndReload()
+		})
+	var vrrps []ifaceVrrpType
+	var ifacePaths []string
+	for _, path := range paths {
+		if strings.HasPrefix(path, *ifaceDir) {
+			ifacePaths = append(ifacePaths, path)
+
+			continue
+		}
+		file, ok := restored[path]
+		if !ok {
+			file = managedFileType{Path: path}
+		}
+		old, ok := saved[path]
+		if !ok {
+			old = managedFileType{Path: path}
+		}
+		if file.Exists {
+			ifaceVrrp, isVrrp, err := parseVrrpPath(path, file.Content)
+			if err != nil {
+				return err
+			}
+			if isVrrp {
+				vrrps = append(vrrps, ifaceVrrp)
+			}
+		}
+		if old.Exists == file.Exists && old.Content == file.Content {
+			continue
+		}
+		plan.add(strings.Join([]string{"restore", path, "on", node.name}, " "),
+			func() error {
+				return node.writeFile(file)
+			},
+			func() error {
+				return node.writeFile(old)
+			})
+		if file.Exists {
+			plan.describe(node.name, []managedFileType{file}, nil, nil)
+		} else {
+			plan.describe(node.name, nil, []string{path}, nil)
+		}
+	}
+	// remove iface before change and add (an old iface can be the vlan device of a new iface)
+	for _, path := range ifacePaths {
+		if _, ok := restored[path]; ok {
+			continue
+		}
+		ifaceOld, err := parseIfaceFile(filepath.Base(path), saved[path].Content)
+		if err != nil {
+			return err
+		}
+		err = plan.removeIfaceSteps(node, ifaceOld)
+		if err != nil {
+			return err
+		}
+	}
+	for _, path := range ifacePaths {
+		file, ok := restored[path]
+		if !ok {
+			continue
+		}
+		old, ok := saved[path]
+		if !ok {
+			old = managedFileType{Path: path}
+		}
+		if old.Exists && old.Content == file.Content {
+			continue
+		}
+		err := plan.restoreIfaceSteps(node, old, file)
+		if err != nil {
+			return err
+		}
+	}
+	plan.add(strings.Join([]string{"reload vrrp on", node.name}, " "),
+		func() error {
+			err := node.syncGroupAndReload()
+			if err != nil {
+				return err
+			}
+			err = node.waitKeepalived()
+			if err != nil {
+				return err
+			}
+			for _, ifaceVrrp := range vrrps {
+				err := node.waitVrrp(ifaceVrrp)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}, nil)
+	plan.describe(node.name, nil, nil, reloadCommands(1))
+
+	return nil
+}
+
+// restoreIfaceSteps : add step for ifup iface with network config file of snapshot on node,
+// with ifdown before if iface exists with an other config. Undo is ifdown and restore old file (and ifup).
+func (plan *planType) restoreIfaceSteps(node nodeOpsType, old, file managedFileType) error {
+	iface := filepath.Base(file.Path)
+	ifaceRestore, err := parseIfaceFile(iface, file.Content)
+	if err != nil {
+		return err
+	}
+	commands := make([]string, 0)
+	var ifaceOld ifaceVrrpType
+	if old.Exists {
+		ifaceOld, err = parseIfaceFile(iface, old.Content)
+		if err != nil {
+			return err
+		}
+		commands = append(postUpCommands(ifaceOld.PostUp, nil), strings.Join([]string{"ifdown", iface, "--force"}, " "))
+	}
+	plan.add(strings.Join([]string{"restore", file.Path, "on", node.name}, " "),
+		func() error {
+			if old.Exists {
+				err := node.ifdownIface(ifaceOld)
+				if err != nil {
+					return err
+				}
+			}
+			err := node.writeFile(file)
+			if err != nil {
+				return err
+			}
+
+			return node.ifupIface(iface)
+		},
+		func() error {
+			current, err := node.readFile(file.Path)
+			if err != nil {
+				return err
+			}
+			if current.Exists && current.Content == file.Content {
+				err = node.ifdownIface(ifaceRestore)
+				if err != nil {
+					// ifup failed before post-up commands, ifdown without them
+					ifaceRestoreWithoutPostUp := ifaceRestore
+					ifaceRestoreWithoutPostUp.PostUp = nil
+					err = node.ifdownIface(ifaceRestoreWithoutPostUp)
+					if err != nil {
+						return err
+					}
+				}
+			}
+			err = node.writeFile(old)
+			if err != nil {
+				return err
+			}
+			if !old.Exists {
+				return nil
+			}
+
+			return node.ifupIface(iface)
+		})
+	plan.describe(node.name, []managedFileType{file}, nil,
+		append(commands, strings.Join([]string{"ifup", iface}, " ")))
+
+	return nil
+}
+
+// parseVrrpPath : Iface, Vrrp_group and Id_vrrp (and options needed for wait instance) of vrrp config file
+// in a Vrrp_group directory, return false if path isn't a vrrp config file.
+func parseVrrpPath(path, content string) (ifaceVrrpType, bool, error) {
+	pathSplit := strings.Split(strings.TrimPrefix(path, *keepalivedDir), "/")
+	if len(pathSplit) != 2 || !strings.HasSuffix(pathSplit[1], ".conf") { // nolint: gomnd
+		return ifaceVrrpType{}, false, nil
+	}
+	fileName := strings.TrimSuffix(pathSplit[1], ".conf")
+	separator := strings.LastIndex(fileName, "_")
+	if separator == -1 {
+		return ifaceVrrpType{}, false, nil
+	}
+	ifaceVrrp, err := parseVrrpFile(ifaceVrrpType{
+		Iface:     fileName[:separator],
+		VrrpGroup: pathSplit[0],
+	}, content)
+	if err != nil {
+		return ifaceVrrp, true, fmt.Errorf("vrrp config file %v : %v", path, err) // nolint: errorlint
+	}
+	ifaceVrrp.IDVrrp = fileName[separator+1:]
+
+	return ifaceVrrp, true, nil
+}
+
+// changeVrrpScriptSteps : add steps for write vrrp script config file and reload keepalived on node,
+// undo is restore old file and reload keepalived.
+func (plan *planType) changeVrrpScriptSteps(node nodeOpsType, vrrpScript vrrpScriptType) error {
+	saved, err := node.readFile(vrrpScriptFilePath(vrrpScript.Name))
+	if err != nil {
+		return err
+	}
+	scriptFile := managedFileType{
+		Exists:  true,
+		Path:    saved.Path,
+		Content: generateScriptFile(vrrpScript),
+	}
+	plan.add(strings.Join([]string{"write vrrp_script on", node.name}, " "),
+		func() error {
+			return node.writeFile(scriptFile)
+		},
+		func() error {
+			err := node.writeFile(saved)
+			if err != nil {
+				return err
+			}
+
+			return node.reloadVrrp()
+		})
+	plan.describe(node.name, []managedFileType{scriptFile}, nil, nil)
+	plan.add(strings.Join([]string{"reload vrrp on", node.name}, " "),
+		func() error {
+			err := node.reloadVrrp()
+			if err != nil {
+				return err
+			}
+
+			return node.waitKeepalived()
+		}, nil)
+	plan.describe(node.name, nil, nil, reloadCommands(1))
+
+	return nil
+}
+
+// addVrrpScriptSteps : add steps for write vrrp script config file if it doesn't exist and reload keepalived on node.
+func (plan *planType) addVrrpScriptSteps(node nodeOpsType, vrrpScript vrrpScriptType) error {
+	saved, err := node.readFile(vrrpScriptFilePath(vrrpScript.Name))
+	if err != nil {
+		return err
+	}
+	if saved.Exists {
+		return nil
+	}
+
+	return plan.changeVrrpScriptSteps(node, vrrpScript)
+}
+
+// removeVrrpScriptSteps : add steps for remove vrrp script config file if it exists and reload keepalived on node,
+// undo is restore old file and reload keepalived.
+func (plan *planType) removeVrrpScriptSteps(node nodeOpsType, name string) error {
+	saved, err := node.readFile(vrrpScriptFilePath(name))
+	if err != nil {
+		return err
+	}
+	if !saved.Exists {
+		return nil
+	}
+	plan.removeKeepalivedFileSteps(node, "vrrp_script", saved)
+
+	return nil
+}
+
+// addVirtualServerSteps : add steps for write virtual server config file if it doesn't exist
+// and reload keepalived on node.
+func (plan *planType) addVirtualServerSteps(node nodeOpsType, virtualServer virtualServerType) error {
+	saved, err := node.readFile(virtualServerFilePath(virtualServer.Name))
+	if err != nil {
+		return err
+	}
+	if saved.Exists {
+		return nil
+	}
+
+	return plan.changeVirtualServerSteps(node, virtualServer)
+}
+
+// removeVirtualServerSteps : add steps for remove virtual server config file if it exists and reload keepalived
+// on node, undo is restore old file and reload keepalived.
+func (plan *planType) removeVirtualServerSteps(node nodeOpsType, name string) error {
+	saved, err := node.readFile(virtualServerFilePath(name))
+	if err != nil {
+		return err
+	}
+	if !saved.Exists {
+		return nil
+	}
+	plan.removeKeepalivedFileSteps(node, "virtual_server", saved)
+
+	return nil
+}
+
+// removeKeepalivedFileSteps : add steps for remove keepalived config file 'saved' and reload keepalived on node,
+// undo is restore file and reload keepalived.
+func (plan *planType) removeKeepalivedFileSteps(node nodeOpsType, kind string, saved managedFileType) {
+	plan.add(strings.Join([]string{"remove", kind, "on", node.name}, " "),
+		func() error {
+			return node.writeFile(managedFileType{Path: saved.Path})
+		},
+		func() error {
+			err := node.writeFile(saved)
+			if err != nil {
+				return err
+			}
+
+			return node.reloadVrrp()
+		})
+	plan.describe(node.name, nil, []string{saved.Path}, nil)
+	plan.add(strings.Join([]string{"reload vrrp on", node.name}, " "),
+		func() error {
+			err := node.reloadVrrp()
+			if err != nil {
+				return err
+			}
+
+			return node.waitKeepalived()
+		}, nil)
+	plan.describe(node.name, nil, nil, reloadCommands(1))
+}
+
+// changeVirtualServerSteps : add steps for write virtual server config file and reload keepalived on node,
+// undo is restore old file and reload keepalived.
+func (plan *planType) changeVirtualServerSteps(node nodeOpsType, virtualServer virtualServerType) error {
+	saved, err := node.readFile(virtualServerFilePath(virtualServer.Name))
+	if err != nil {
+		return err
+	}
+	virtualServerFile := managedFileType{
+		Exists:  true,
+		Path:    saved.Path,
+		Content: generateVirtualServerFile(virtualServer),
+	}
+	plan.add(strings.Join([]string{"write virtual_server on", node.name}, " "),
+		func() error {
+			return node.writeFile(virtualServerFile)
+		},
+		func() error {
+			err := node.writeFile(saved)
+			if err != nil {
+				return err
+			}
+
+			return node.reloadVrrp()
+		})
+	plan.describe(node.name, []managedFileType{virtualServerFile}, nil, nil)
+	plan.add(strings.Join([]string{"reload vrrp on", node.name}, " "),
+		func() error {
+			err := node.reloadVrrp()
+			if err != nil {
+				return err
+			}
+
+			return node.waitKeepalived()
+		}, nil)
+	plan.describe(node.name, nil, nil, reloadCommands(1))
+
+	return nil
+}
+
+// changeRealServerSteps : add steps for add, remove or drain one real server in virtual server file on node
+// and reload, file restored and reload on rollback.
+func (plan *planType) changeRealServerSteps(node nodeOpsType, virtualServerName string,
+	realServer realServerType, action string) error {
+	saved, err := node.readFile(virtualServerFilePath(virtualServerName))
+	if err != nil {
+		return err
+	}
+	if !saved.Exists {
+		return fmt.Errorf("virtual_server config file %v doesn't exist on %v", saved.Path, node.name)
+	}
+	virtualServer, err := parseVirtualServerFile(virtualServerName, saved.Content)
+	if err != nil {
+		return err
+	}
+	virtualServer, err = changeRealServers(virtualServer, realServer, action)
+	if err != nil {
+		return err
+	}
+	virtualServerFile := managedFileType{
+		Exists:  true,
+		Path:    saved.Path,
+		Content: generateVirtualServerFile(virtualServer),
+	}
+	if virtualServerFile.Content == saved.Content {
+		return nil
+	}
+	plan.add(strings.Join([]string{"write virtual_server on", node.name}, " "),
+		func() error {
+			return node.writeFile(virtualServerFile)
+		},
+		func() error {
+			err := node.writeFile(saved)
+			if err != nil {
+				return err
+			}
+
+			return node.reloadVrrp()
+		})
+	plan.describe(node.name, []managedFileType{virtualServerFile}, nil, nil)
+	plan.add(strings.Join([]string{"reload vrrp on", node.name}, " "),
+		func() error {
+			err := node.reloadVrrp()
+			if err != nil {
+				return err
+			}
+
+			return node.waitKeepalived()
+		}, nil)
+	plan.describe(node.name, nil, nil, reloadCommands(1))
+
+	return nil
+}
+
+// vrrpWithPrio : vrrp configuration of instance read in config file on node, with priority changed for this node.
+func vrrpWithPrio(node nodeOpsType, instance vrrpStateType, prio string) (ifaceVrrpType, error) {
+	ifaceVrrp := ifaceVrrpType{
+		Iface:     instance.Iface,
+		VrrpGroup: instance.VrrpGroup,
+		IDVrrp:    instance.IDVrrp,
+	}
+	file, err := node.readFile(vrrpFilePath(ifaceVrrp))
+	if err != nil {
+		return ifaceVrrp, err
+	}
+	if !file.Exists {
+		return ifaceVrrp, fmt.Errorf("vrrp config file %v doesn't exist on %v", file.Path, node.name)
+	}
+	vrrpRead, err := parseVrrpFile(ifaceVrrp, file.Content)
+	if err != nil {
+		return ifaceVrrp, err
+	}
+	vrrpRead.IPVipOnly = true
+	if node.master {
+		vrrpRead.PrioMaster = prio
+	} else {
+		vrrpRead.PrioSlave = prio
+		vrrpRead.PrioNodes = map[string]string{node.name: prio}
+	}
+
+	return vrrpRead, nil
+}
+
+// changeVrrpPriosSteps : add steps for write vrrp config files with new priorities then reload keepalived on node,
+// undo is restore old files and reload keepalived.
+func (plan *planType) changeVrrpPriosSteps(node nodeOpsType, ifaceVrrps []ifaceVrrpType) error {
+	savedFiles := make([]managedFileType, 0, len(ifaceVrrps))
+	vrrpFiles := make([]managedFileType, 0, len(ifaceVrrps))
+	for _, ifaceVrrp := range ifaceVrrps {
+		saved, err := node.readFile(vrrpFilePath(ifaceVrrp))
+		if err != nil {
+			return err
+		}
+		savedFiles = append(savedFiles, saved)
+		vrrpFile, err := plan.generatedFile(node, ifaceVrrp, saved.Path)
+		if err != nil {
+			return err
+		}
+		vrrpFiles = append(vrrpFiles, vrrpFile)
+	}
+	plan.add(strings.Join([]string{"write vrrp priorities on", node.name}, " "),
+		func() error {
+			for _, ifaceVrrp := range ifaceVrrps {
+				err := node.addVrrp(ifaceVrrp)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+		func() error {
+			for _, file := range savedFiles {
+				err := node.writeFile(file)
+				if err != nil {
+					return err
+				}
+			}
+
+			return node.syncGroupAndReload()
+		})
+	plan.describe(node.name, vrrpFiles, nil, nil)
+	plan.add(strings.Join([]string{"reload vrrp on", node.name}, " "),
+		func() error {
+			err := node.syncGroupAndReload()
+			if err != nil {
+				return err
+			}
+
+			return node.waitKeepalived()
+		}, nil)
+	plan.describe(node.name, nil, nil, reloadCommands(1))
+
+	return nil
+}
+
+// waitVrrpMasterStep : add step for wait state MASTER of instances on node (no undo).
+func (plan *planType) waitVrrpMasterStep(node nodeOpsType, instances []vrrpStateType) {
+	plan.waitVrrpStatesStep(strings.Join([]string{"MASTER on", node.name}, " "), node, instances,
+		func(state string) bool {
+			return state == vrrpStateMaster
+		})
+}
+
+// waitVrrpNotMasterStep : add step for wait instances on node aren't in state MASTER (VIPs on other nodes, no undo).
+func (plan *planType) waitVrrpNotMasterStep(node nodeOpsType, instances []vrrpStateType) {
+	plan.waitVrrpStatesStep(strings.Join([]string{"no MASTER on", node.name}, " "), node, instances,
+		func(state string) bool {
+			return state != vrrpStateMaster
+		})
+}
+
+// waitVrrpStatesStep : add step for wait state of each instances on node is ok for check (no undo).
+func (plan *planType) waitVrrpStatesStep(what string, node nodeOpsType, instances []vrrpStateType,
+	check func(string) bool) {
+	plan.add(strings.Join([]string{"wait", what}, " "),
+		func() error {
+			return pollUntil(what, func() (bool, string) {
+				vrrpStates, err := node.readVrrpStates()
+				if err != nil {
+					return false, err.Error()
+				}
+				states := make(map[string]string)
+				for _, vrrpState := range vrrpStates {
+					states[vrrpStateKey(vrrpState)] = vrrpState.State
+				}
+				for _, instance := range instances {
+					if state := states[vrrpStateKey(instance)]; !check(state) {
+						return false, strings.Join([]string{"vrrp instance", instance.Instance, "in state", state}, " ")
+					}
+				}
+
+				return true, ""
+			})
+		}, nil)
+}
@@ -0,0 +1,517 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	auth "github.com/abbot/go-http-auth"
+	"github.com/gorilla/mux"
+)
+
+// v2ErrorCode : code of error in JSON of /v2/ API for status code.
+func v2ErrorCode(statusCode int) string {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return "invalid_request"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusMethodNotAllowed:
+		return "method_not_allowed"
+	case http.StatusConflict:
+		return "conflict"
+	default:
+		return "internal_error"
+	}
+}
+
+// v2Error : write error of /v2/ API in JSON with code, message and errors of validate() on each field.
+func v2Error(w http.ResponseWriter, statusCode int, message string, validationErrors ...validationErrorType) {
+	if message == "" {
+		message = http.StatusText(statusCode)
+	}
+	js, err := json.Marshal(v2ErrorType{
+		Code:    v2ErrorCode(statusCode),
+		Message: message,
+		Errors:  validationErrors,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(js)
+}
+
+// v2MethodNotAllowed : handler for /v2/ resource with a method not allowed.
+func v2MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	v2Error(w, http.StatusMethodNotAllowed, strings.Join([]string{"method", r.Method, "not allowed on", r.URL.Path}, " "))
+}
+
+// callLegacy : call handler of legacy API with vars of route and json body (nil for empty body),
+// the request keeps headers (authentication) and query (dry_run) of request on /v2/ API.
+func callLegacy(handler http.HandlerFunc, r *http.Request, vars map[string]string,
+	jsonBody interface{}) (*jobResponseWriter, error) {
+	body := []byte{}
+	if jsonBody != nil {
+		var err error
+		body, err = json.Marshal(jsonBody)
+		if err != nil {
+			return nil, err
+		}
+	}
+	legacyRequest := mux.SetURLVars(r.Clone(r.Context()), vars)
+	legacyRequest.Body = ioutil.NopCloser(bytes.NewReader(body))
+	jw := &jobResponseWriter{header: make(http.Header)}
+	handler(jw, legacyRequest)
+	if jw.statusCode == 0 {
+		jw.statusCode = http.StatusOK
+	}
+
+	return jw, nil
+}
+
+// v2Response : write response of legacy API, error in JSON if status code >= 400
+// and statusCode (201 or 204) if legacy response is OK without body.
+func v2Response(w http.ResponseWriter, jw *jobResponseWriter, statusCode int) {
+	if jw.statusCode >= http.StatusBadRequest {
+		var validationErrors validationErrorsType
+		if jw.statusCode == http.StatusBadRequest &&
+			json.Unmarshal(jw.body.Bytes(), &validationErrors) == nil && len(validationErrors.Errors) != 0 {
+			messages := make([]string, 0, len(validationErrors.Errors))
+			for _, validationError := range validationErrors.Errors {
+				messages = append(messages, validationError.Message)
+			}
+			v2Error(w, jw.statusCode, strings.Join(messages, ", "), validationErrors.Errors...)
+
+			return
+		}
+		v2Error(w, jw.statusCode, strings.TrimSpace(jw.body.String()))
+
+		return
+	}
+	if jw.body.Len() == 0 {
+		w.WriteHeader(statusCode)
+
+		return
+	}
+	if jw.statusCode == http.StatusOK && statusCode == http.StatusCreated {
+		jw.statusCode = http.StatusCreated
+	}
+	for key, values := range jw.header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(jw.statusCode)
+	_, _ = w.Write(jw.body.Bytes())
+}
+
+// v2Legacy : /v2/ handler which only calls a legacy handler with body of request.
+func v2Legacy(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var jsonBody interface{}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			v2Error(w, http.StatusInternalServerError, err.Error())
+
+			return
+		}
+		if len(bytes.TrimSpace(body)) != 0 {
+			jsonBody = json.RawMessage(body)
+		}
+		jw, err := callLegacy(handler, r, mux.Vars(r), jsonBody)
+		if err != nil {
+			v2Error(w, http.StatusInternalServerError, err.Error())
+
+			return
+		}
+		v2Response(w, jw, http.StatusNoContent)
+	}
+}
+
+// v2DecodeBody : decode json body of request on resource, over current values for PATCH.
+func v2DecodeBody(w http.ResponseWriter, r *http.Request, resource interface{}) bool {
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(resource)
+	if err != nil {
+		v2Error(w, http.StatusBadRequest, strings.Join([]string{"bad json :", err.Error()}, " "))
+
+		return false
+	}
+
+	return true
+}
+
+// v2PutInterface : PUT and PATCH on /v2/interfaces/{iface} : add iface if it doesn't exist,
+// change it (with move of Id_vrrp if needed) if it's different, nothing if it's the same.
+func v2PutInterface(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			v2Error(w, http.StatusUnauthorized, "")
+
+			return
+		}
+	}
+	vars := mux.Vars(r)
+	// body read before lock, decoded over current values for PATCH
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		v2Error(w, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	// read and compare with changes of other requests finished
+	mutex.Lock()
+	jw, err := callLegacy(importIfaceVrrp, r, vars, nil)
+	if err != nil {
+		mutex.Unlock()
+		v2Error(w, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+	exists := jw.statusCode != http.StatusNotFound
+	if !exists && r.Method == http.MethodPatch {
+		mutex.Unlock()
+		v2Error(w, http.StatusNotFound, strings.Join([]string{"iface", vars["iface"], "doesn't exist"}, " "))
+
+		return
+	}
+	if jw.statusCode >= http.StatusBadRequest && exists {
+		mutex.Unlock()
+		v2Response(w, jw, http.StatusNoContent)
+
+		return
+	}
+	var ifaceVrrpCurrent ifaceVrrpType
+	if exists {
+		err := json.Unmarshal(jw.body.Bytes(), &ifaceVrrpCurrent)
+		if err != nil {
+			mutex.Unlock()
+			v2Error(w, http.StatusInternalServerError, err.Error())
+
+			return
+		}
+	}
+	var ifaceVrrp ifaceVrrpType
+	if r.Method == http.MethodPatch {
+		ifaceVrrp = ifaceVrrpCurrent
+	}
+	if !v2DecodeBody(w, r, &ifaceVrrp) {
+		mutex.Unlock()
+
+		return
+	}
+	ifaceVrrp.Iface = vars["iface"]
+	handler := addIfaceVrrp
+	handlerVars := vars
+	statusCode := http.StatusCreated
+	switch {
+	case !exists:
+	case ifaceVrrpCurrent.IDVrrp != "" && ifaceVrrp.IDVrrp != "" && ifaceVrrpCurrent.IDVrrp != ifaceVrrp.IDVrrp:
+		handler = moveIDIfaceVrrp
+		handlerVars = map[string]string{
+			"iface":       vars["iface"],
+			"old_Id_vrrp": ifaceVrrpCurrent.IDVrrp,
+		}
+		statusCode = http.StatusNoContent
+	default:
+		jw, err = callLegacy(checkIfaceVrrp, r, vars, ifaceVrrp)
+		if err != nil {
+			mutex.Unlock()
+			v2Error(w, http.StatusInternalServerError, err.Error())
+
+			return
+		}
+		if jw.statusCode >= http.StatusBadRequest {
+			mutex.Unlock()
+			v2Response(w, jw, http.StatusNoContent)
+
+			return
+		}
+		if jw.statusCode != http.StatusPartialContent {
+			// same configuration
+			mutex.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+
+			return
+		}
+		handler = changeIfaceVrrp
+		statusCode = http.StatusNoContent
+	}
+	// legacy handler locks mutex
+	mutex.Unlock()
+	jw, err = callLegacy(handler, r, handlerVars, ifaceVrrp)
+	if err != nil {
+		v2Error(w, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+	v2Response(w, jw, statusCode)
+}
+
+// v2DeleteInterface : DELETE on /v2/interfaces/{iface} : remove iface with current configuration.
+func v2DeleteInterface(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	jw, err := callLegacy(importIfaceVrrp, r, vars, nil)
+	if err != nil {
+		v2Error(w, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+	if jw.statusCode >= http.StatusBadRequest {
+		v2Response(w, jw, http.StatusNoContent)
+
+		return
+	}
+	jw, err = callLegacy(removeIfaceVrrp, r, vars, json.RawMessage(jw.body.Bytes()))
+	if err != nil {
+		v2Error(w, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+	v2Response(w, jw, http.StatusNoContent)
+}
+
+// v2PutVrrpScript : PUT and PATCH on /v2/vrrp-scripts/{name} : add vrrp_script if it doesn't exist,
+// change it if it's different, nothing if it's the same.
+func v2PutVrrpScript(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			v2Error(w, http.StatusUnauthorized, "")
+
+			return
+		}
+	}
+	vars := mux.Vars(r)
+	// body read before lock, decoded over current values for PATCH
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		v2Error(w, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	// read and compare with changes of other requests finished
+	mutex.Lock()
+	exists := checkVrrpScriptExists(vars["name"])
+	if !exists && r.Method == http.MethodPatch {
+		mutex.Unlock()
+		v2Error(w, http.StatusNotFound, strings.Join([]string{"vrrp_script", vars["name"], "doesn't exist"}, " "))
+
+		return
+	}
+	var vrrpScriptCurrent vrrpScriptType
+	if exists {
+		vrrpScriptCurrent, err = readVrrpScriptFile(vars["name"])
+		if err != nil {
+			mutex.Unlock()
+			v2Error(w, http.StatusInternalServerError, err.Error())
+
+			return
+		}
+	}
+	var vrrpScript vrrpScriptType
+	if r.Method == http.MethodPatch {
+		vrrpScript = vrrpScriptCurrent
+	}
+	if !v2DecodeBody(w, r, &vrrpScript) {
+		mutex.Unlock()
+
+		return
+	}
+	vrrpScript.Name = vars["name"]
+	handler := addVrrpScript
+	statusCode := http.StatusCreated
+	same := exists && generateScriptFile(vrrpScript) == generateScriptFile(vrrpScriptCurrent)
+	// legacy handler locks mutex
+	mutex.Unlock()
+	if exists {
+		if same {
+			w.WriteHeader(http.StatusNoContent)
+
+			return
+		}
+		handler = changeVrrpScript
+		statusCode = http.StatusNoContent
+	}
+	jw, err := callLegacy(handler, r, vars, vrrpScript)
+	if err != nil {
+		v2Error(w, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+	v2Response(w, jw, statusCode)
+}
+
+// v2DeleteVrrpScript : DELETE on /v2/vrrp-scripts/{name} : remove vrrp_script.
+func v2DeleteVrrpScript(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	jw, err := callLegacy(removeVrrpScript, r, vars, vrrpScriptType{Name: vars["name"]})
+	if err != nil {
+		v2Error(w, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+	v2Response(w, jw, http.StatusNoContent)
+}
+
+// v2PutVirtualServer : PUT and PATCH on /v2/virtual-servers/{name} : add virtual_server if it doesn't exist,
+// change it if it's different, nothing if it's the same.
+func v2PutVirtualServer(w http.ResponseWriter, r *http.Request) {
+	if configHtpasswd() != "" {
+		htpasswd := auth.HtpasswdFileProvider(configHtpasswd())
+		authenticator := auth.BasicAuth{
+			Realm:   "Basic Realm",
+			Secrets: htpasswd,
+		}
+		usercheck := authenticator.CheckAuth(r)
+		if usercheck == "" {
+			v2Error(w, http.StatusUnauthorized, "")
+
+			return
+		}
+	}
+	vars := mux.Vars(r)
+	// body read before lock, decoded over current values for PATCH
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		v2Error(w, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	// read and compare with changes of other requests finished
+	mutex.Lock()
+	exists := checkVirtualServerExists(vars["name"])
+	if !exists && r.Method == http.MethodPatch {
+		mutex.Unlock()
+		v2Error(w, http.StatusNotFound, strings.Join([]string{"virtual_server", vars["name"], "doesn't exist"}, " "))
+
+		return
+	}
+	var virtualServerCurrent virtualServerType
+	if exists {
+		virtualServerCurrent, err = readVirtualServerFile(vars["name"])
+		if err != nil {
+			mutex.Unlock()
+			v2Error(w, http.StatusInternalServerError, err.Error())
+
+			return
+		}
+	}
+	var virtualServer virtualServerType
+	if r.Method == http.MethodPatch {
+		virtualServer = virtualServerCurrent
+	}
+	if !v2DecodeBody(w, r, &virtualServer) {
+		mutex.Unlock()
+
+		return
+	}
+	virtualServer.Name = vars["name"]
+	handler := addVirtualServer
+	statusCode := http.StatusCreated
+	same := exists && generateVirtualServerFile(virtualServer) == generateVirtualServerFile(virtualServerCurrent)
+	// legacy handler locks mutex
+	mutex.Unlock()
+	if exists {
+		if same {
+			w.WriteHeader(http.StatusNoContent)
+
+			return
+		}
+		handler = changeVirtualServer
+		statusCode = http.StatusNoContent
+	}
+	jw, err := callLegacy(handler, r, vars, virtualServer)
+	if err != nil {
+		v2Error(w, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+	v2Response(w, jw, statusCode)
+}
+
+// v2DeleteVirtualServer : DELETE on /v2/virtual-servers/{name} : remove virtual_server.
+func v2DeleteVirtualServer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	jw, err := callLegacy(removeVirtualServer, r, vars, virtualServerType{Name: vars["name"]})
+	if err != nil {
+		v2Error(w, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+	v2Response(w, jw, http.StatusNoContent)
+}
+
+// v2RealServer : PUT (add or change), DELETE and POST .../drain on
+// /v2/virtual-servers/{name}/real-servers/{ip}/{port} with real server of path.
+func v2RealServer(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		var realServer realServerType
+		if r.Method == http.MethodPut && !v2DecodeBody(w, r, &realServer) {
+			return
+		}
+		realServer.IP = vars["ip"]
+		port, err := strconv.Atoi(vars["port"])
+		if err != nil {
+			v2Error(w, http.StatusBadRequest, strings.Join([]string{"bad port", vars["port"]}, " "))
+
+			return
+		}
+		realServer.Port = port
+		jw, err := callLegacy(handler, r, vars, realServer)
+		if err != nil {
+			v2Error(w, http.StatusInternalServerError, err.Error())
+
+			return
+		}
+		v2Response(w, jw, http.StatusNoContent)
+	}
+}
+
+// v2Routes : routes of /v2/ API with resources and HTTP verbs (on master), served with legacy routes.
+func v2Routes(router *mux.Router) {
+	router.HandleFunc("/v2/interfaces", v2Legacy(listIfaceVrrp)).Methods(http.MethodGet)
+	router.HandleFunc("/v2/interfaces/{iface}", v2Legacy(importIfaceVrrp)).Methods(http.MethodGet)
+	router.HandleFunc("/v2/interfaces/{iface}", v2PutInterface).Methods(http.MethodPut, http.MethodPatch)
+	router.HandleFunc("/v2/interfaces/{iface}", v2DeleteInterface).Methods(http.MethodDelete)
+	router.HandleFunc("/v2/interfaces/{iface}/diff", v2Legacy(diffIfaceVrrp)).Methods(http.MethodPost)
+	router.HandleFunc("/v2/vrrp-scripts", v2Legacy(listVrrpScript)).Methods(http.MethodGet)
+	router.HandleFunc("/v2/vrrp-scripts/{name}", v2Legacy(checkVrrpScript)).Methods(http.MethodGet)
+	router.HandleFunc("/v2/vrrp-scripts/{name}", v2PutVrrpScript).Methods(http.MethodPut, http.MethodPatch)
+	router.HandleFunc("/v2/vrrp-scripts/{name}", v2DeleteVrrpScript).Methods(http.MethodDelete)
+	router.HandleFunc("/v2/vrrp-scripts/{name}/diff", v2Legacy(diffVrrpScript)).Methods(http.MethodPost)
+	router.HandleFunc("/v2/virtual-servers", v2Legacy(listVirtualServer)).Methods(http.MethodGet)
+	router.HandleFunc("/v2/virtual-servers/{name}", v2Legacy(checkVirtualServer)).Methods(http.MethodGet)
+	router.HandleFunc("/v2/virtual-servers/{name}", v2PutVirtualServer).Methods(http.MethodPut, http.MethodPatch)
+	router.HandleFunc("/v2/virtual-servers/{name}", v2DeleteVirtualServer).Methods(http.MethodDelete)
+	router.HandleFunc("/v2/virtual-servers/{name}/diff", v2Legacy(diffVirtualServer)).Methods(http.MethodPost)
+	router.HandleFunc("/v2/virtual-servers/{name}/real-servers/{ip}/{port}",
+		v2RealServer(addRealServer)).Methods(http.MethodPut)
+	router.HandleFunc("/v2/virtual-servers/{name}/real-servers/{ip}/{port}",
+		v2RealServer(removeRealServer)).Methods(http.MethodDelete)
+	router.HandleFunc("/v2/virtual-servers/{name}/real-servers/{ip}/{port}/drain",
+		v2RealServer(drainRealServer)).Methods(http.MethodPost)
+	router.MethodNotAllowedHandler = http.HandlerFunc(v2MethodNotAllowed)
+}